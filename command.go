@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"strings"
+)
+
+// A Command defines a named subcommand with its own option struct and argument
+// slice.  Commands are passed to New along with the global option struct and
+// argument slice.  Global options are accepted on the command line before the
+// subcommand name, and everything after the name is parsed by the subcommand.
+type Command struct {
+	name			string
+	text			string
+	v				[]interface{}
+	err				string				// a problem with the name, reported by New
+}
+
+// Define a new subcommand.  The name may be followed by a colon and a short
+// help text, e.g. "build:Compile the packages".  The remaining arguments are an
+// option struct pointer, an argument slice pointer, or both, followed by any
+// nested subcommands.  An invalid name is reported by New as a definition
+// problem.
+//
+//   build := option.NewCommand("build:Compile the packages", &buildOpts, &files)
+//   op, err := option.New(&globalOpts, build)
+//
+func NewCommand( name string, v ...interface{} ) *Command {
+	c := &Command{v: v}
+	if r := strings.SplitN(name, delimiter, 2); len(r) > 1 {
+		name = r[0]
+		c.text = r[1]
+	}
+	if name == "" || name[0] == '-' {
+		c.err = "invalid command name ("+name+")"
+	}
+	c.name = name
+	return c
+}
+
+// Returns the name of this subcommand, or a blank string for the top level
+// command.
+func (o *Option) Name() string {
+	return o.name
+}
+
+// Returns the subcommand invoked on the command line, or nil if no subcommand
+// was given.
+func (o *Option) Subcommand() *Option {
+	return o.sub
+}
+
+// Returns the named subcommand, or nil if it was not defined.  The returned
+// option object may be used to print help text for that subcommand.
+func (o *Option) Command(name string) *Option {
+	for _,s := range o.subs {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// return true if the supplied argument is the name of a defined subcommand
+func (o *Option) isCommand(arg string) bool {
+	for _,c := range o.commands {
+		if c.name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// create an option object for each subcommand and check its definition.  Any
+// problems found are added to those of this command.
+func (o *Option) defineCommands() {
+	for _,c := range o.commands {
+		if c.err != "" {
+			o.defError(c.err)
+			continue
+		}
+		if o.Command(c.name) != nil {
+			o.defError("command already defined ("+c.name+")")
+			continue
+		}
+		sub := newOption([]string{o.Cmd() + " " + c.name})
+		sub.name = c.name
+		sub.text = c.text
+		for _,setting := range o.settings {
+			sub.settings = append(sub.settings, setting)
+			setting(sub)
+		}
+		if o.envPrefix != "" {
+			sub.envPrefix = o.envPrefix + "_" + envKey(c.name)
+		}
+		// the subcommand reads its section of the parent's config file
+		sub.configPath = ""
+		v2 := sub.configure(c.v)
+		if len(v2) > 2 {
+			sub.defError("expected one or two arguments")
+			v2 = nil
+		}
+		sub.defineAll(v2)
+		for _,msg := range sub.defErrs {
+			o.defErrs = append(o.defErrs, c.name + ": " + msg)
+		}
+		o.subs = append(o.subs, sub)
+	}
+}
+
+// parse the arguments of the invoked subcommand, which is given the remainder
+// of the argument vector.  The option structs of the other subcommands are
+// left untouched.
+func (o *Option) runCommands() error {
+	if o.cmdIndex == 0 {
+		return nil
+	}
+	sub := o.Command(o.argv[o.cmdIndex])
+	sub.argv = append(sub.argv, o.argv[o.cmdIndex+1:]...)
+	sub.conf = o.conf
+	sub.confPrefix = o.confPrefix + sub.name + "-"
+	o.sub = sub
+	return sub.assign()
+}
+
+// return an UnknownCommandError if subcommands are defined and the command
+// line has an argument where a subcommand name is expected.  An argument is
+// allowed only if there is an argument slice to receive it.
+func (o *Option) checkCommand() error {
+	if len(o.subs) == 0 || o.hasArgSlice {
+		return nil
+	}
+	for _,v := range o.vdata {
+		if v.typ == typ_arg {
+			return &UnknownCommandError{Name: v.val}
+		}
+	}
+	return nil
+}
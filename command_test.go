@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+)
+
+func TestCommand( t *testing.T ) {
+
+	type global struct{ Verbose bool }
+	type build struct{ Output string; Race bool }
+	type deploy struct{ Env string }
+
+    myTest("Given a subcommand with global options", t, func() {
+		var g global
+		var b build
+		var files []string
+		var d deploy
+		argv := []string{"mypath/tool", "-v", "build", "-o", "out", "--race", "a.go", "b.go"}
+		op,err := NewFromArgs(argv, &g,
+			NewCommand("build:Compile the packages", &b, &files),
+			NewCommand("deploy:Deploy to an environment", &d),
+		)
+		ShouldNotError( err )
+		ShouldBeTrue( g.Verbose )
+		ShouldEqual( b.Output, "out" )
+		ShouldBeTrue( b.Race )
+		ShouldEqual( files, []string{"a.go", "b.go"} )
+		ShouldEqual( d.Env, "" )
+		ShouldEqual( op.Subcommand().Name(), "build" )
+		ShouldEqual( op.Subcommand().Cmd(), "mypath/tool build" )
+		ShouldEqual( op.Command("deploy").Name(), "deploy" )
+		ShouldBeTrue( op.Command("test") == nil )
+	})
+
+    myTest("Given no subcommand", t, func() {
+		var g global
+		var b build
+		op,err := NewFromArgs([]string{"tool", "-v"}, &g, NewCommand("build", &b))
+		ShouldNotError( err )
+		ShouldBeTrue( g.Verbose )
+		ShouldBeTrue( op.Subcommand() == nil )
+	})
+
+    myTest("Given nested subcommands", t, func() {
+		var r struct{ Force bool }
+		var name [1]string
+		argv := []string{"tool", "remote", "remove", "-f", "origin"}
+		op,err := NewFromArgs(argv,
+			NewCommand("remote",
+				NewCommand("add"),
+				NewCommand("remove", &r, &name),
+			),
+		)
+		ShouldNotError( err )
+		ShouldEqual( op.Subcommand().Subcommand().Name(), "remove" )
+		ShouldBeTrue( r.Force )
+		ShouldEqual( name[0], "origin" )
+	})
+
+    myTest("Given a global option after the subcommand name", t, func() {
+		var g global
+		var d deploy
+		argv := []string{"tool", "deploy", "-v"}
+		_,err := NewFromArgs(argv, &g, NewCommand("deploy", &d))
+		ShouldError( err, "Invalid command line option: (v)" )
+	})
+
+    myTest("Given a mistyped subcommand", t, func() {
+		var g global
+		var b build
+		op,err := NewFromArgs([]string{"tool", "-v", "biuld"}, &g, NewCommand("build", &b))
+		ShouldError( err, "Unknown command: (biuld)" )
+		var args []string
+		op,err = NewFromArgs([]string{"tool", "biuld"}, &g, &args, NewCommand("build", &b))
+		ShouldNotError( err )
+		ShouldBeTrue( op.Subcommand() == nil )
+		ShouldEqual( args, []string{"biuld"} )
+	})
+
+    myTest("Given a subcommand that was not invoked", t, func() {
+		var g global
+		var d struct{ Env string `default:"staging"` }
+		op,err := NewFromArgs([]string{"tool"}, &g, NewCommand("deploy", &d))
+		ShouldNotError( err )
+		ShouldBeTrue( op.Subcommand() == nil )
+		ShouldEqual( d.Env, "" )
+	})
+
+    myTest("Given invalid command definitions", t, func() {
+		ShouldPanic(func(){
+			var g global
+			NewFromArgs([]string{"tool"}, &g, NewCommand("-x"))
+		})
+		ShouldPanic(func(){
+			var g global
+			NewFromArgs([]string{"tool"}, &g, NewCommand("x"), NewCommand("x"))
+		})
+	})
+
+    myTest("Given help and usage for subcommands", t, func() {
+		var g global
+		var b build
+		var files []string
+		var d deploy
+		op,_ := NewFromArgs([]string{"mypath/tool"}, &g,
+			NewCommand("build:Compile the packages", &b, &files),
+			NewCommand("deploy:Deploy to an environment", &d),
+		)
+		ShouldEqual( op.HelpString(),
+			"SYNOPSIS\n"+
+			"    tool [OPTION] COMMAND\n\n"+
+			"COMMANDS\n"+
+			"    build       Compile the packages\n"+
+			"    deploy      Deploy to an environment\n\n"+
+			"OPTION\n"+
+			"    -v, --[no-]verbose\n\n")
+		ShouldEqual( op.Command("build").HelpString(),
+			"SYNOPSIS\n"+
+			"    tool build [OPTIONS] [string]...\n\n"+
+			"OPTIONS\n"+
+			"    -o string, --output=string\n\n"+
+			"    -r, --[no-]race\n\n")
+		str := captureStdout( func(){
+			op.Usage()
+		})
+		ShouldEqual( str, "Usage: tool [OPTION] COMMAND\nCommands: build, deploy\n" )
+	})
+
+}
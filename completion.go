@@ -0,0 +1,566 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"reflect"
+)
+
+// the hidden first argument with which the completion scripts call back into
+// the program
+const complete_cmd = "__complete"
+
+// replaced by tests
+var exit = os.Exit
+
+// Returns a shell completion script for this command.  The shell may be "bash",
+// "zsh" or "fish".  The script completes the unix and gnu keys of every option
+// and the names of subcommands, and suggests the values of bool options and of
+// options with an enum key in the struct tag:
+//
+//   Level string `opt:"l:level:name:Log level" enum:"debug,info,warn"`
+//
+// The script is usually written to a file at install time, or evaluated by the
+// user's shell startup file.  The program must offer a way to print it, such as
+// a Completion string option of its own, which would be used as
+// source <(mycmd --completion=bash).
+//
+// The values of options with a Completer are listed by the program itself.  For
+// these options the script runs the program with the hidden first argument
+// __complete followed by the words of the command line, up to and including the
+// word being completed.  New then prints the candidates, one per line, and
+// exits.  Without a Completer, __complete is an ordinary argument.
+func (o *Option) Completion(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return o.bashCompletion(), nil
+	case "zsh":
+		return o.zshCompletion(), nil
+	case "fish":
+		return o.fishCompletion(), nil
+	}
+	return "", fmt.Errorf("unsupported shell (%s)", shell)
+}
+
+// Completer registers a function that lists the values of an option for the
+// completion scripts, e.g. the cluster names of --cluster.  The key is the unix
+// or gnu key of the option, and the function is given the partial value typed
+// so far.  It may return each candidate followed by a tab and a description.
+//
+//   op, err := option.New(&opts, option.Completer("cluster", listClusters))
+//
+// Subcommands inherit the completer, so it applies to the option with this key
+// in every command.
+func Completer(key string, fn func(prefix string) []string) Setting {
+	return func(o *Option) {
+		o.completers[key] = fn
+	}
+}
+
+// Returns the completion candidates for a partial command line.  The words
+// follow the command name, and the last word is the one being completed (an
+// empty string for a new word).  Each candidate may be followed by a tab and a
+// description.  Options, subcommands and option values are completed, while
+// file names are left to the shell.
+func (o *Option) Complete(words []string) []string {
+	words = joinAssign(words)
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	c := o
+	var value *opt
+	for _,w := range words[:len(words)-1] {
+		if w == "--" {
+			// only arguments follow
+			return nil
+		}
+		if value != nil {
+			value = nil
+			continue
+		}
+		if x := c.keyOption(w); x != nil {
+			if !x.isFlag() {
+				value = x
+			}
+			continue
+		}
+		if s := c.Command(w); s != nil {
+			c = s
+		}
+	}
+	if value != nil {
+		return c.completeValue(value, cur)
+	}
+	if m := rx.gnuKeywordAssign.FindStringSubmatch(cur); m != nil {
+		if x := c.lookup(m[1]); x != nil {
+			return c.completeValue(x, m[2])
+		}
+		return nil
+	}
+	var list []string
+	if strings.HasPrefix(cur, "-") {
+		for _,x := range c.completionList() {
+			for _,key := range x.allKeys() {
+				list = appendCandidate(list, cur, key, x.text)
+			}
+		}
+		return list
+	}
+	for _,s := range c.subs {
+		list = appendCandidate(list, cur, s.name, s.text)
+	}
+	return list
+}
+
+// print the candidates for the words following __complete and exit
+func (o *Option) completeRequest() {
+	for _,c := range o.Complete(o.argv[2:]) {
+		fmt.Println(c)
+	}
+	exit(0)
+}
+
+// return the candidate values of an option
+func (o *Option) completeValue(x *opt, prefix string) []string {
+	for _,key := range []string{x.gnu_key, x.u_key} {
+		if fn,ok := o.completers[key]; ok && key != "" {
+			return fn(prefix)
+		}
+	}
+	var list []string
+	for _,c := range x.choices() {
+		list = appendCandidate(list, prefix, c, "")
+	}
+	return list
+}
+
+// append a candidate with an optional description if it begins with prefix
+func appendCandidate(list []string, prefix, candidate, text string) []string {
+	if !strings.HasPrefix(candidate, prefix) {
+		return list
+	}
+	if text = shortText(text); text != "" {
+		candidate += "\t" + text
+	}
+	return append(list, candidate)
+}
+
+// bash splits --key=value into the words --key, = and value.  Put them back
+// together.
+func joinAssign(words []string) []string {
+	var joined []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if w == "=" && len(joined) > 0 {
+			w = joined[len(joined)-1] + w
+			joined = joined[:len(joined)-1]
+			if i+1 < len(words) {
+				i++
+				w += words[i]
+			}
+		}
+		joined = append(joined, w)
+	}
+	return joined
+}
+
+// return the option named by a command line word such as -p or --port.  The
+// last key of a cluster of unix keys (-xvf) is returned, or nil if the value of
+// an option is attached to the cluster (-p8080).
+func (o *Option) keyOption(word string) *opt {
+	if m := rx.gnuKeyword.FindStringSubmatch(word); m != nil {
+		return o.lookup(m[1])
+	}
+	if o.isFlags(word) {
+		keys, _, attached := o.splitFlags(word[1:])
+		if attached {
+			return nil
+		}
+		return o.lookup(keys[len(keys)-1])
+	}
+	return nil
+}
+
+
+// return the name of a shell function for the supplied command path
+func funcName(path ...string) string {
+	return "_" + rx.nonWord.ReplaceAllString(strings.Join(path, "_"), "_")
+}
+
+// return true if a Completer was registered for either key of an option
+func (o *Option) hasCompleter(x *opt) bool {
+	for _,key := range []string{x.gnu_key, x.u_key} {
+		if _,ok := o.completers[key]; ok && key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// return the options of this command, including the automatic --config option
+// of a command with a config file
+func (o *Option) completionList() []*opt {
+	list := o.optionList
+	if o.configPath != "" && !o.keys[config_key] {
+		conf := &opt{gnu_key: config_key, placeholder: "file", text: "Read option values from file"}
+		conf.fld = reflect.ValueOf(new(string)).Elem()
+		list = append(list[:len(list):len(list)], conf)
+	}
+	return list
+}
+
+// return true if the values of this option are file names
+func (x opt) completeFiles() bool {
+	t := x.fld.Type()
+	if x.repeatable() {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String && !isCustom(t)
+}
+
+// return the keys of this option as they are typed on the command line
+func (x opt) keyList() []string {
+	var keys []string
+	if x.u_key != "" {
+		keys = append(keys, "-"+x.u_key)
+	}
+	if x.gnu_key != "" {
+		keys = append(keys, "--"+x.gnu_key)
+	}
+	return keys
+}
+
+// return the keys of this option, followed by --no-key if it is negatable
+func (x opt) allKeys() []string {
+	if x.negatable() {
+		return append(x.keyList(), "--no-"+x.gnu_key)
+	}
+	return x.keyList()
+}
+
+// quote a string for bash or zsh
+func shQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// quote a string for fish
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+}
+
+// return the first line of the help text of an option or subcommand
+func shortText(s string) string {
+	if i := strings.Index(s, "\n"); i > -1 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func (o *Option) bashCompletion() string {
+	fn := funcName(o.cmd) + "_complete"
+	str := "# bash completion for " + o.cmd + "\n\n"
+	str += fn + "() {\n"
+	str += "\tlocal cur prev eq path i\n"
+	str += "\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n"
+	str += "\tprev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n"
+	// --key=value is split into three words by COMP_WORDBREAKS
+	str += "\tif [ \"$cur\" = \"=\" ]; then\n"
+	str += "\t\teq=1; cur=\"\"\n"
+	str += "\telif [ \"$prev\" = \"=\" ]; then\n"
+	str += "\t\teq=1; prev=\"${COMP_WORDS[COMP_CWORD-2]}\"\n"
+	str += "\tfi\n"
+	str += "\tpath=\"\"\n"
+	str += "\tfor (( i=1; i<COMP_CWORD; i++ )); do\n"
+	str += "\t\tcase \"$path/${COMP_WORDS[i]}\" in\n"
+	if paths := o.commandPaths(""); len(paths) > 0 {
+		str += "\t\t" + strings.Join(paths, "|") + ") path=\"$path/${COMP_WORDS[i]}\" ;;\n"
+	}
+	str += "\t\tesac\n"
+	str += "\tdone\n"
+	str += "\tcase \"$path\" in\n"
+	str += o.bashCase(o.cmd, "")
+	str += "\tesac\n"
+	str += "}\n\n"
+	str += "complete -F " + fn + " " + o.cmd + "\n"
+	return str
+}
+
+// return the quoted command paths of all subcommands (/build, /deploy/prod)
+func (o *Option) commandPaths(prefix string) []string {
+	var paths []string
+	for _,s := range o.subs {
+		p := prefix + "/" + s.name
+		paths = append(paths, shQuote(p))
+		paths = append(paths, s.commandPaths(p)...)
+	}
+	return paths
+}
+
+// return the case entry of this command and its subcommands
+func (o *Option) bashCase(cmd, path string) string {
+	str := "\t" + shQuote(path) + ")\n"
+	str += "\t\tcase \"$prev\" in\n"
+	var keys []string
+	for _,x := range o.completionList() {
+		keys = append(keys, x.allKeys()...)
+		if x.isFlag() {
+			// a bool value may only be given with --key=value
+			if x.gnu_key != "" {
+				str += "\t\t--" + x.gnu_key + ") if [ -n \"$eq\" ]; then "
+				str += "COMPREPLY=( $(compgen -W 'true false' -- \"$cur\") ); return; fi ;;\n"
+			}
+			continue
+		}
+		str += "\t\t" + strings.Join(x.keyList(), "|") + ") "
+		str += o.bashValues(cmd, x) + "return ;;\n"
+	}
+	str += "\t\tesac\n"
+	str += "\t\tif [[ \"$cur\" == -* ]]; then\n"
+	str += "\t\t\tCOMPREPLY=( $(compgen -W " + shQuote(strings.Join(keys, " ")) + " -- \"$cur\") )\n"
+	if len(o.subs) > 0 {
+		var names []string
+		for _,s := range o.subs {
+			names = append(names, s.name)
+		}
+		str += "\t\telse\n"
+		str += "\t\t\tCOMPREPLY=( $(compgen -W " + shQuote(strings.Join(names, " ")) + " -- \"$cur\") )\n"
+	} else if o.hasArgSlice {
+		str += "\t\telse\n"
+		str += "\t\t\tCOMPREPLY=( $(compgen -f -- \"$cur\") )\n"
+	}
+	str += "\t\tfi\n"
+	str += "\t\t;;\n"
+	for _,s := range o.subs {
+		str += s.bashCase(cmd, path + "/" + s.name)
+	}
+	return str
+}
+
+// return the bash commands that complete the value of an option
+func (o *Option) bashValues(cmd string, x *opt) string {
+	if o.hasCompleter(x) {
+		words := cmd + " " + complete_cmd + " \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null | cut -f1"
+		return "COMPREPLY=( $(compgen -W \"$(" + words + ")\" -- \"$cur\") ); "
+	}
+	if choices := x.choices(); choices != nil {
+		return "COMPREPLY=( $(compgen -W " + shQuote(strings.Join(choices, " ")) + " -- \"$cur\") ); "
+	}
+	if x.completeFiles() {
+		return "COMPREPLY=( $(compgen -f -- \"$cur\") ); "
+	}
+	return ""
+}
+
+func (o *Option) zshCompletion() string {
+	str := "#compdef " + o.cmd + "\n\n"
+	if len(o.completers) > 0 {
+		// the words of the command line up to the cursor are passed back
+		str += funcName(o.cmd, "callback") + "() {\n"
+		str += "\tlocal -a args values\n"
+		str += "\targs=( ${(z)LBUFFER} )\n"
+		str += "\t[[ $LBUFFER == *' ' ]] && args+=( '' )\n"
+		str += "\tvalues=( ${(f)\"$(" + o.cmd + " " + complete_cmd + " \"${(@Q)args[2,-1]}\" 2>/dev/null)\"} )\n"
+		str += "\tcompadd -- ${values%%$'\\t'*}\n"
+		str += "}\n\n"
+	}
+	str += o.zshFunc([]string{o.cmd})
+	str += funcName(o.cmd) + " \"$@\"\n"
+	return str
+}
+
+// return the completion function of this command, followed by those of its
+// subcommands
+func (o *Option) zshFunc(path []string) string {
+	str := funcName(path...) + "() {\n"
+	if len(o.subs) > 0 {
+		str += "\tlocal curcontext=\"$curcontext\" state line\n"
+		str += "\ttypeset -A opt_args\n"
+	}
+	specs := []string{}
+	for _,x := range o.completionList() {
+		specs = append(specs, o.zshSpecs(path[0], x)...)
+	}
+	if len(o.subs) > 0 {
+		specs = append(specs, "'1: :->command'", "'*:: :->args'")
+	} else if o.hasArgSlice {
+		specs = append(specs, "'*:file:_files'")
+	}
+	str += "\t_arguments -s -C"
+	for _,spec := range specs {
+		str += " \\\n\t\t" + spec
+	}
+	str += "\n"
+	if len(o.subs) > 0 {
+		str += "\tcase $state in\n"
+		str += "\tcommand)\n"
+		str += "\t\tlocal -a commands\n"
+		str += "\t\tcommands=(\n"
+		for _,s := range o.subs {
+			str += "\t\t\t" + shQuote(zshEscape(s.name) + ":" + shortText(s.text)) + "\n"
+		}
+		str += "\t\t)\n"
+		str += "\t\t_describe -t commands command commands\n"
+		str += "\t\t;;\n"
+		str += "\targs)\n"
+		str += "\t\tcase $line[1] in\n"
+		for _,s := range o.subs {
+			str += "\t\t" + s.name + ") " + funcName(append(path[:len(path):len(path)], s.name)...) + " ;;\n"
+		}
+		str += "\t\tesac\n"
+		str += "\t\t;;\n"
+		str += "\tesac\n"
+	}
+	str += "}\n\n"
+	for _,s := range o.subs {
+		str += s.zshFunc(append(path[:len(path):len(path)], s.name))
+	}
+	return str
+}
+
+// return the _arguments specs of an option, one for each key
+func (o *Option) zshSpecs(cmd string, x *opt) []string {
+	var specs []string
+	keys := x.keyList()
+	exclude := ""
+	if x.repeatable() {
+		exclude = "*"
+	} else if len(x.allKeys()) > 1 {
+		exclude = "(" + strings.Join(x.allKeys(), " ") + ")"
+	}
+	help := ""
+	if text := shortText(x.text); text != "" {
+		help = "[" + zshEscape(text) + "]"
+	}
+	for _,key := range keys {
+		spec := exclude + key
+		switch {
+		case x.isFlag() && key[1] != '-':
+			// no value
+		case x.isFlag():
+			spec += "=-" + help + "::bool:(true false)"
+			specs = append(specs, shQuote(spec))
+			continue
+		case key[1] != '-':
+			spec += "+"
+		default:
+			spec += "="
+		}
+		spec += help
+		if !x.isFlag() {
+			spec += ":" + zshEscape(x.placeholder) + ":" + o.zshAction(cmd, x)
+		}
+		specs = append(specs, shQuote(spec))
+	}
+	if x.negatable() {
+		specs = append(specs, shQuote(exclude + "--no-" + x.gnu_key + help))
+	}
+	return specs
+}
+
+// return the zsh action that completes the value of an option
+func (o *Option) zshAction(cmd string, x *opt) string {
+	if o.hasCompleter(x) {
+		return funcName(cmd, "callback")
+	}
+	if choices := x.choices(); choices != nil {
+		return "(" + strings.Join(choices, " ") + ")"
+	}
+	if x.completeFiles() {
+		return "_files"
+	}
+	return " "
+}
+
+// escape the characters that are special in an _arguments spec
+func zshEscape(s string) string {
+	for _,c := range []string{`\`, "[", "]", ":"} {
+		s = strings.Replace(s, c, `\`+c, -1)
+	}
+	return s
+}
+
+func (o *Option) fishCompletion() string {
+	str := "# fish completion for " + o.cmd + "\n\n"
+	str += o.fishLines(o.cmd, nil)
+	return str
+}
+
+// return the complete commands of this command and its subcommands.  The path
+// holds the names of the enclosing subcommands.
+func (o *Option) fishLines(cmd string, path []string) string {
+	var cond []string
+	for _,name := range path {
+		cond = append(cond, "__fish_seen_subcommand_from "+name)
+	}
+	if len(o.subs) > 0 {
+		var names []string
+		for _,s := range o.subs {
+			names = append(names, s.name)
+		}
+		cond = append(cond, "not __fish_seen_subcommand_from "+strings.Join(names, " "))
+	}
+	prefix := "complete -c " + cmd
+	if len(cond) > 0 {
+		prefix += " -n " + fishQuote(strings.Join(cond, "; and "))
+	}
+	str := ""
+	if !o.hasArgSlice {
+		str += prefix + " -f\n"
+	}
+	for _,s := range o.subs {
+		str += prefix + " -f -a " + fishQuote(s.name)
+		if text := shortText(s.text); text != "" {
+			str += " -d " + fishQuote(text)
+		}
+		str += "\n"
+	}
+	for _,x := range o.completionList() {
+		str += prefix
+		if x.u_key != "" {
+			str += " -s " + x.u_key
+		}
+		if x.gnu_key != "" {
+			str += " -l " + x.gnu_key
+		}
+		if !x.isFlag() {
+			str += o.fishValues(cmd, x)
+		}
+		if text := shortText(x.text); text != "" {
+			str += " -d " + fishQuote(text)
+		}
+		str += "\n"
+		if x.negatable() {
+			str += prefix + " -l no-" + x.gnu_key
+			if text := shortText(x.text); text != "" {
+				str += " -d " + fishQuote(text)
+			}
+			str += "\n"
+		}
+	}
+	for _,s := range o.subs {
+		str += s.fishLines(cmd, append(path[:len(path):len(path)], s.name))
+	}
+	return str
+}
+
+// return the complete arguments that describe the value of an option
+func (o *Option) fishValues(cmd string, x *opt) string {
+	if o.hasCompleter(x) {
+		words := cmd + " " + complete_cmd + " (commandline -opc)[2..-1] (commandline -ct)"
+		return " -x -a " + fishQuote("(" + words + ")")
+	}
+	if choices := x.choices(); choices != nil {
+		return " -x -a " + fishQuote(strings.Join(choices, " "))
+	}
+	if x.completeFiles() {
+		return " -r -F"
+	}
+	return " -x"
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCompletion( t *testing.T ) {
+
+	type buildSt struct{
+		Output		string	`opt:"o:output:file:Output file"`
+	}
+	type mySt struct{
+		Level		string	`opt:"l:level:name:Log level" enum:"debug,info,warn"`
+		Port		int		`opt:"p:port:number:Port to listen on"`
+		Debug		bool	`opt:"d:debug::Don't stop"`
+		Include		[]string
+	}
+
+	newTest := func() *Option {
+		var my mySt
+		var build buildSt
+		var files []string
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, NewCommand("build:Compile it", &build, &files))
+		ShouldNotError( err )
+		return op
+	}
+
+	contains := func(str string, parts ...string) {
+		for _,p := range parts {
+			if !strings.Contains(str, p) {
+				ShouldEqual( str, p )
+			}
+		}
+	}
+
+    myTest("Given an unsupported shell", t, func() {
+		_,err := newTest().Completion("tcsh")
+		ShouldError( err, "unsupported shell (tcsh)" )
+	})
+
+    myTest("Given a bash completion script", t, func() {
+		str,err := newTest().Completion("bash")
+		ShouldNotError( err )
+		contains(str,
+			"_mycmd_complete() {",
+			`-l|--level) COMPREPLY=( $(compgen -W 'debug info warn' -- "$cur") ); return ;;`,
+			`-p|--port) return ;;`,
+			`--debug) if [ -n "$eq" ]; then COMPREPLY=( $(compgen -W 'true false' -- "$cur") ); return; fi ;;`,
+			`-i|--include) COMPREPLY=( $(compgen -f -- "$cur") ); return ;;`,
+			`compgen -W '-l --level -p --port -d --debug --no-debug -i --include'`,
+			`compgen -W 'build'`,
+			`'/build') path="$path/${COMP_WORDS[i]}" ;;`,
+			`-o|--output) COMPREPLY=( $(compgen -f -- "$cur") ); return ;;`,
+			"complete -F _mycmd_complete mycmd\n",
+		)
+	})
+
+    myTest("Given a zsh completion script", t, func() {
+		str,err := newTest().Completion("zsh")
+		ShouldNotError( err )
+		contains(str,
+			"#compdef mycmd\n",
+			`'(-l --level)-l+[Log level]:name:(debug info warn)'`,
+			`'(-l --level)--level=[Log level]:name:(debug info warn)'`,
+			`'(-d --debug --no-debug)-d[Don'\''t stop]'`,
+			`'(-d --debug --no-debug)--debug=-[Don'\''t stop]::bool:(true false)'`,
+			`'(-d --debug --no-debug)--no-debug[Don'\''t stop]'`,
+			`'*-i+:string:_files'`,
+			`'build:Compile it'`,
+			"build) _mycmd_build ;;",
+			"_mycmd_build() {",
+			"'*:file:_files'",
+		)
+	})
+
+    myTest("Given a fish completion script", t, func() {
+		str,err := newTest().Completion("fish")
+		ShouldNotError( err )
+		contains(str,
+			"complete -c mycmd -n 'not __fish_seen_subcommand_from build' -f\n",
+			"complete -c mycmd -n 'not __fish_seen_subcommand_from build' -f -a 'build' -d 'Compile it'\n",
+			"-s l -l level -x -a 'debug info warn' -d 'Log level'\n",
+			"-s p -l port -x -d 'Port to listen on'\n",
+			"-s d -l debug -d 'Don\\'t stop'\n",
+			"complete -c mycmd -n 'not __fish_seen_subcommand_from build' -l no-debug -d 'Don\\'t stop'\n",
+			"complete -c mycmd -n '__fish_seen_subcommand_from build' -s o -l output -r -F -d 'Output file'\n",
+		)
+	})
+
+    myTest("Given a value that is not in the enum", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--level=trace"}, &my)
+		ShouldError( err, `expected one of (debug, info, warn) "level"` )
+		_,err = NewFromArgs([]string{arg0, "-l", "warn"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Level, "warn" )
+	})
+
+}
+
+func TestCompleteCallback( t *testing.T ) {
+
+	type deploySt struct{
+		Cluster		string	`opt:"c:cluster:name:Target cluster"`
+		Force		bool
+	}
+	type mySt struct{
+		Level		string	`opt:"l:level:name:Log level" enum:"debug,info,warn"`
+		Verbose		bool	`opt:"v:verbose::Say more"`
+	}
+
+	clusters := func(prefix string) []string {
+		var list []string
+		for _,c := range []string{"east\tUS East", "eu-west", "west"} {
+			if strings.HasPrefix(c, prefix) {
+				list = append(list, c)
+			}
+		}
+		return list
+	}
+
+	newTest := func(args ...string) *Option {
+		var my mySt
+		var deploy deploySt
+		argv := append([]string{"mycmd"}, args...)
+		deployCmd := NewCommand("deploy:Deploy it", &deploy)
+		op,err := NewFromArgs(argv, &my, deployCmd, NewCommand("status", &deploy), Completer("cluster", clusters))
+		ShouldNotError( err )
+		return op
+	}
+
+    myTest("Given a partial command line", t, func() {
+		for _,tst := range []struct{ words []string; expected string }{
+			{[]string{""}, "[deploy\tDeploy it status]"},
+			{[]string{"--l"}, "[--level\tLog level]"},
+			{[]string{"-l", ""}, "[debug info warn]"},
+			{[]string{"--level=w"}, "[warn]"},
+			{[]string{"-v", "de"}, "[deploy\tDeploy it]"},
+			{[]string{"deploy", "-"}, "[-c\tTarget cluster --cluster\tTarget cluster -f --force --no-force]"},
+			{[]string{"-l", "info", "deploy", "-fc", "e"}, "[east\tUS East eu-west]"},
+			{[]string{"status", "--cluster", "=", "w"}, "[west]"},
+			{[]string{"deploy", "--cluster", "="}, "[east\tUS East eu-west west]"},
+		}{
+			ShouldEqual( fmt.Sprint(newTest().Complete(tst.words)), tst.expected )
+		}
+	})
+
+    myTest("Given the hidden __complete argument", t, func() {
+		code := -1
+		exit = func(c int) { code = c }
+		defer func() { exit = os.Exit }()
+		var err error
+		str := captureStdout( func(){
+			var my mySt
+			var deploy deploySt
+			_,err = NewFromArgs([]string{"mycmd", "__complete", "deploy", "--cluster=e"}, &my,
+				NewCommand("deploy:Deploy it", &deploy), Completer("cluster", clusters))
+		})
+		ShouldNotError( err )
+		ShouldEqual( code, 0 )
+		ShouldEqual( str, "east\tUS East\neu-west\n" )
+	})
+
+    myTest("Given __complete without a completer", t, func() {
+		code := -1
+		exit = func(c int) { code = c }
+		defer func() { exit = os.Exit }()
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{"mycmd", "__complete", "-v"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( code, -1 )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( args, []string{"__complete"} )
+	})
+
+    myTest("Given completion scripts with a completer", t, func() {
+		str,_ := newTest().Completion("bash")
+		ShouldBeTrue( strings.Contains(str, `-c|--cluster) COMPREPLY=( $(compgen -W "$(mycmd __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1)" -- "$cur") ); return ;;`) )
+		str,_ = newTest().Completion("zsh")
+		ShouldBeTrue( strings.Contains(str, "_mycmd_callback() {\n") )
+		ShouldBeTrue( strings.Contains(str, `'(-c --cluster)--cluster=[Target cluster]:name:_mycmd_callback'`) )
+		str,_ = newTest().Completion("fish")
+		ShouldBeTrue( strings.Contains(str, `-s c -l cluster -x -a '(mycmd __complete (commandline -opc)[2..-1] (commandline -ct))' -d 'Target cluster'`) )
+	})
+
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"fmt"
+	"bytes"
+	"sort"
+	"bufio"
+	"reflect"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+)
+
+const config_key = "config"
+
+// ConfigFile names a config file to be read when no --config option is given
+// on the command line.  It is not an error if this file does not exist.
+//
+// Config files supply values for options that are absent from the command
+// line.  Keys are the gnu keywords of the options.  Files ending in .json are
+// read as a JSON object.  All other files are read as lines of key=value pairs,
+// in the manner of an INI or simple TOML file.  Blank lines and lines beginning
+// with # or ; are ignored, as is a comment after a value (port = 8080 # web),
+// unless the # or ; is within quotes.  A [section] heading is prepended to the
+// keys that follow it (section-key).  Subcommand options are read from the
+// section, or nested JSON object, of the same name.
+//
+// The command line takes precedence over environment variables, environment
+// variables take precedence over the config file, and the config file takes
+// precedence over tag defaults.
+func ConfigFile(path string) Setting {
+	return func(o *Option) {
+		o.configPath = path
+	}
+}
+
+// Read the config file named with --config=path, or the default config file.
+// The --config option is recognised automatically unless the option struct
+// defines its own config key.
+func (o *Option) loadConfig() error {
+	path := o.configPath
+	explicit := false
+	if !o.keys[config_key] {
+		for _,ndx := range o.vmap[config_key] {
+			o.vdata[ndx].typ = typ_option
+			path = o.vdata[ndx].val
+			explicit = true
+		}
+	}
+	if path == "" {
+		if explicit {
+			return fmt.Errorf("missing config file name")
+		}
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	conf, err := parseConfig(path, data)
+	if err != nil {
+		return err
+	}
+	// a config file named on a subcommand's own command line has no sections
+	o.conf = conf
+	o.confPrefix = ""
+	return nil
+}
+
+// return the config file values of an option.  The entries of a map option may
+// also be given in a section or nested object of the same name.
+func (o *Option) confValues(x *opt) ([]string, bool) {
+	if x.gnu_key == "" {
+		return nil, false
+	}
+	key := o.confPrefix + x.gnu_key
+	if vals,ok := o.conf[key]; ok {
+		return vals, true
+	}
+	if x.fld.Kind() != reflect.Map {
+		return nil, false
+	}
+	var vals []string
+	for k,v := range o.conf {
+		if strings.HasPrefix(k, key + "-") {
+			for _,val := range v {
+				vals = append(vals, k[len(key)+1:] + "=" + val)
+			}
+		}
+	}
+	sort.Strings(vals)
+	return vals, len(vals) > 0
+}
+
+// parse the config file data according to the file extension
+func parseConfig(path string, data []byte) (map[string][]string, error) {
+	conf := make(map[string][]string)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		flattenJSON(conf, "", m)
+		return conf, nil
+	}
+	prefix := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section := strings.TrimSpace(line[1:len(line)-1])
+			prefix = strings.Replace(section, ".", "-", -1) + "-"
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("%s: syntax error (line %d)", path, n)
+		}
+		key := prefix + strings.TrimSpace(line[:i])
+		val := strings.TrimSpace(line[i+1:])
+		if len(val) > 1 && val[0] == '[' && val[len(val)-1] == ']' {
+			// a simple array of values
+			for _,item := range strings.Split(val[1:len(val)-1], ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					conf[key] = append(conf[key], unquote(item))
+				}
+			}
+			continue
+		}
+		conf[key] = append(conf[key], unquote(val))
+	}
+	return conf, scanner.Err()
+}
+
+// flatten nested JSON objects into section-key names
+func flattenJSON(conf map[string][]string, prefix string, m map[string]interface{}) {
+	for k,v := range m {
+		key := prefix + k
+		switch x := v.(type) {
+		case map[string]interface{}:
+			flattenJSON(conf, key + "-", x)
+		case []interface{}:
+			for _,item := range x {
+				conf[key] = append(conf[key], fmt.Sprint(item))
+			}
+		case nil:
+		default:
+			conf[key] = append(conf[key], fmt.Sprint(x))
+		}
+	}
+}
+
+// remove matching single or double quotes from a value
+// remove a trailing comment that begins with # or ; after a blank and is not
+// within quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 1; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case (c == '#' || c == ';') && (line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquote(s string) string {
+	if len(s) > 1 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"testing"
+	"path/filepath"
+)
+
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig( t *testing.T ) {
+
+	type mySt struct{
+		ListenAddr	string
+		Port		int		`default:"8080"`
+		Debug		bool
+		Answer		int		`default:"42"`
+	}
+
+	json_path := writeConfig(t, "my.json",
+		`{"listen-addr": "0.0.0.0", "port": 9090, "debug": true, "build": {"output": "bin"}}`)
+	ini_path := writeConfig(t, "my.conf",
+		"# comment\n"+
+		"listen-addr = \"0.0.0.0\"\n"+
+		"port=9090 # inline comment\n"+
+		"; another comment\n"+
+		"debug = yes\t; inline comment\n\n"+
+		"[build]\n"+
+		"output = 'bin'\n")
+
+    myTest("Given a comment character within a value", t, func() {
+		var my struct{ Name string; Tags []string }
+		path := writeConfig(t, "quoted.conf",
+			"name = \"a # b; c\" # comment\n"+
+			"tags = [x#1, 'y ;2'] ; comment\n")
+		_,err := NewFromArgs([]string{arg0, "--config=" + path}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Name, "a # b; c" )
+		ShouldEqual( my.Tags, []string{"x#1", "y ;2"} )
+	})
+
+    myTest("Given a JSON config file", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--config=" + json_path}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "0.0.0.0" )
+		ShouldEqual( my.Port, 9090 )
+		ShouldBeTrue( my.Debug )
+		ShouldEqual( my.Answer, 42 )
+	})
+
+    myTest("Given a key=value config file", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--config", ini_path}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "0.0.0.0" )
+		ShouldEqual( my.Port, 9090 )
+		ShouldBeTrue( my.Debug )
+		ShouldEqual( my.Answer, 42 )
+	})
+
+    myTest("Given the command line and environment should take precedence", t, func() {
+		t.Setenv("MYAPP_LISTEN_ADDR", "localhost")
+		var my mySt
+		argv := []string{arg0, "--config=" + ini_path, "-p", "80"}
+		_,err := NewFromArgs(argv, &my, EnvPrefix("MYAPP"))
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "localhost" )
+		ShouldEqual( my.Port, 80 )
+		ShouldBeTrue( my.Debug )
+	})
+
+    myTest("Given a default config file and a subcommand", t, func() {
+		var my mySt
+		var b struct{ Output string }
+		argv := []string{arg0, "build"}
+		_,err := NewFromArgs(argv, &my, ConfigFile(json_path), NewCommand("build", &b))
+		ShouldNotError( err )
+		ShouldEqual( my.Port, 9090 )
+		ShouldEqual( b.Output, "bin" )
+	})
+
+    myTest("Given a missing default config file", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0}, &my, ConfigFile(ini_path + ".missing"))
+		ShouldNotError( err )
+		ShouldEqual( my.Port, 8080 )
+	})
+
+    myTest("Given a missing config file on the command line", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--config=" + ini_path + ".missing"}, &my)
+		ShouldError( err )
+		_,err = NewFromArgs([]string{arg0, "--config"}, &my)
+		ShouldError( err, "Missing value for option: (config)" )
+		_,err = NewFromArgs([]string{arg0, "--config="}, &my)
+		ShouldError( err, "missing config file name" )
+	})
+
+    myTest("Given invalid config files", t, func() {
+		var my mySt
+		bad := writeConfig(t, "bad.conf", "port\n")
+		_,err := NewFromArgs([]string{arg0, "--config=" + bad}, &my)
+		ShouldError( err, bad + ": syntax error (line 1)" )
+		bad = writeConfig(t, "bad.conf", "port = eighty\n")
+		_,err = NewFromArgs([]string{arg0, "--config=" + bad}, &my)
+		ShouldError( err, `strconv.ParseInt: parsing "eighty": invalid syntax "port"` )
+		bad = writeConfig(t, "bad.json", "{")
+		_,err = NewFromArgs([]string{arg0, "--config=" + bad}, &my)
+		ShouldError( err )
+	})
+
+    myTest("Given an option struct with its own config key", t, func() {
+		var my struct{ Config string }
+		_,err := NewFromArgs([]string{arg0, "--config=" + ini_path}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Config, ini_path )
+	})
+
+}
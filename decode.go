@@ -7,8 +7,10 @@ package option
 import (
 	"time"
 	"errors"
+	"regexp"
 	"reflect"
 	"strconv"
+	"encoding"
 )
 
 const (
@@ -19,9 +21,32 @@ const (
 	format_offset_datetime	= "2006-01-02 15:04:05 -0700"
 )
 
+// Value is the interface to an option field of a custom type.  Any field whose
+// type (or pointer to type) implements Value or encoding.TextUnmarshaler is
+// decoded by that interface rather than by its kind.  If the type also has a
+// Placeholder() string method, it names the option value in the help text.
+// Otherwise the lower case type name is used.
+//
+//   type Level int
+//   func (l *Level) Set(s string) error { ... }
+//   func (l Level) String() string { ... }
+//
+type Value interface {
+	String() string
+	Set(string) error
+}
+
+var (
+	durationType		= reflect.TypeOf(time.Duration(0))
+	durationDayWeek		= regexp.MustCompile(`(\d+\.?\d*|\.\d+)([dw])`)
+	valueType			= reflect.TypeOf((*Value)(nil)).Elem()
+	textUnmarshalerType	= reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
 // setValue will set the value of a supplied scalar variable of most types
 // from a string value. Will return any conversion, syntax or parse errors.
-// Allowed types: int8-64, uint8-64, float32-64, bool, and time.Time.
+// Allowed types: int8-64, uint8-64, float32-64, bool, time.Time, and
+// time.Duration.
 // Allowed bool values:  True, False, Yes, No, 1, 0 (case insensitive)
 //
 // Large numeric values may be shortened using numeric suffixes as follows:
@@ -52,11 +77,17 @@ func setValue(x interface{}, val string) error {
 
 func setScalar(v1 reflect.Value, val string) error {
 	var err error
+	if v1.Kind() == reflect.Struct && isTimeType(v1.Type()) {
+		return set_time(v1, val)
+	}
+	if v1.Type() == durationType {
+		return set_duration(v1, val)
+	}
+	if isCustom(v1.Type()) {
+		return set_custom(v1, val)
+	}
 	switch v1.Kind() {
 	case reflect.Struct:
-		if isTimeType(v1.Type()) {
-			return set_time(v1, val)
-		}
 		return errors.New("type not allowed: struct")
 	case reflect.String:
 		v1.SetString(val)
@@ -78,6 +109,44 @@ func setScalar(v1 reflect.Value, val string) error {
 	return err
 }
 
+// decode a value of a custom type with its Set or UnmarshalText method
+func set_custom(v1 reflect.Value, val string) error {
+	ptr := v1
+	if v1.Kind() == reflect.Ptr {
+		if v1.IsNil() {
+			v1.Set(reflect.New(v1.Type().Elem()))
+		}
+	} else {
+		ptr = v1.Addr()
+	}
+	switch x := ptr.Interface().(type) {
+	case Value:
+		return x.Set(val)
+	case encoding.TextUnmarshaler:
+		return x.UnmarshalText([]byte(val))
+	}
+	return errors.New("type not allowed: " + v1.Type().String())
+}
+
+// decode a time.Duration such as 1h30m or 90s.  In addition to the units of
+// time.ParseDuration, d (day) and w (week) are accepted, e.g. 1w2d or 1.5d.
+func set_duration(v1 reflect.Value, val string) error {
+	val = durationDayWeek.ReplaceAllStringFunc(val, func(s string) string {
+		m := durationDayWeek.FindStringSubmatch(s)
+		n, _ := strconv.ParseFloat(m[1], 64)
+		hours := 24.0
+		if m[2] == "w" {
+			hours *= 7
+		}
+		return strconv.FormatFloat(n * hours, 'f', -1, 64) + "h"
+	})
+	d, err := time.ParseDuration(val)
+	if err == nil {
+		v1.SetInt(int64(d))
+	}
+	return err
+}
+
 func set_bool(v1 reflect.Value, val string) error {
 	val = toLower(val)
 	if val == "true" || val == "yes" || val == "on" || val == "1" {
@@ -88,7 +157,7 @@ func set_bool(v1 reflect.Value, val string) error {
 		v1.SetBool(false)
 		return nil
 	}
-	return errors.New("invalid value for bool")
+	return ErrInvalidBool
 }
 
 func set_int(v1 reflect.Value, val string) error {
@@ -96,7 +165,7 @@ func set_int(v1 reflect.Value, val string) error {
 	v, err := strconv.Atoi(val)
 	if err == nil {
 		if v1.OverflowInt(int64(v)) {
-			return errors.New("Overflow")
+			return ErrOverflow
 		}
 		v1.SetInt(int64(v))
 	}
@@ -117,7 +186,7 @@ func set_uint(v1 reflect.Value, val string) error {
 	v, err := strconv.Atoi(val)
 	if err == nil {
 		if v1.OverflowUint(uint64(v)) {
-			return errors.New("Overflow")
+			return ErrOverflow
 		}
 		v1.SetUint(uint64(v))
 	}
@@ -224,6 +293,36 @@ func isTimeType(v interface{}) bool {
 	return v == reflect.TypeOf(time.Time{})
 }
 
+// return true if a type is decoded by its Value or TextUnmarshaler interface
+func isCustom(t reflect.Type) bool {
+	if isTimeType(t) {
+		return false
+	}
+	for _,x := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if x.Implements(valueType) || x.Implements(textUnmarshalerType) {
+			return true
+		}
+	}
+	return false
+}
+
+// return the name of a type as shown in the help text
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	if !isCustom(t) {
+		return t.String()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if p,ok := reflect.New(t).Interface().(interface{ Placeholder() string }); ok {
+		return p.Placeholder()
+	}
+	return toLower(t.Name())
+}
+
 // Horked from unicode package
 func toLower(s string) string {
 	if len(s) == 0 {
@@ -261,6 +360,9 @@ func upper(r byte) byte {
 }
 
 func isScalar(v1 reflect.Value) bool {
+	if isCustom(v1.Type()) {
+		return true
+	}
 	switch v1.Kind() {
 	case reflect.Bool, reflect.Int, reflect.String,
 		 reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
@@ -5,11 +5,36 @@
 package option
 
 import (
+	"net"
 	"time"
+	"errors"
 	"testing"
 	"reflect"
+	"math/big"
 )
 
+type level int
+
+func (l *level) Set(s string) error {
+	switch s {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("invalid level")
+	}
+	return nil
+}
+
+func (l level) String() string {
+	return [...]string{"", "low", "high"}[l]
+}
+
+func (l level) Placeholder() string {
+	return "low|high"
+}
+
 func Test_decode_numeric_types(t *testing.T) {
 
 	myTest("Decode all numeric types", t, func() {
@@ -204,6 +229,85 @@ func Test_decode_Force_Errors(t *testing.T) {
 
 }
 
+func Test_decode_custom_types(t *testing.T) {
+
+	myTest("Decode Value and TextUnmarshaler types", t, func() {
+
+		var v_IP      net.IP
+		var v_BigInt  big.Int
+		var v_BigPtr  *big.Int
+		var v_Level   level
+
+		ShouldNotError( setValue(&v_IP,      "10.0.0.1") )
+		ShouldNotError( setValue(&v_BigInt,  "123456789012345678901234567890") )
+		ShouldNotError( setValue(&v_BigPtr,  "42") )
+		ShouldNotError( setValue(&v_Level,   "high") )
+
+		ShouldEqual( v_IP.String(),     "10.0.0.1" )
+		ShouldEqual( v_BigInt.String(), "123456789012345678901234567890" )
+		ShouldEqual( v_BigPtr.String(), "42" )
+		ShouldEqual( v_Level.String(),  "high" )
+
+		ShouldError( setValue(&v_IP,     "10.0.0") )
+		ShouldError( setValue(&v_Level,  "medium") )
+
+		ShouldEqual( typeName(reflect.TypeOf(v_IP)),    "ip" )
+		ShouldEqual( typeName(reflect.TypeOf(v_BigPtr)), "int" )
+		ShouldEqual( typeName(reflect.TypeOf(v_Level)), "low|high" )
+		ShouldEqual( typeName(reflect.TypeOf(time.Time{})), "time.Time" )
+	})
+
+	myTest("Custom types in an option struct", t, func() {
+		var my struct{
+			Addr	net.IP
+			Level	level
+			Peers	[]net.IP
+		}
+		argv := []string{arg0, "-a", "10.0.0.1", "--level=low", "-p", "10.0.0.2", "-p", "10.0.0.3"}
+		_,err := NewFromArgs(argv, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Addr.String(), "10.0.0.1" )
+		ShouldEqual( my.Level, level(1) )
+		ShouldEqual( len(my.Peers), 2 )
+		ShouldEqual( my.Peers[1].String(), "10.0.0.3" )
+		_,err = NewFromArgs([]string{arg0, "--level=medium"}, &my)
+		ShouldError( err, `invalid level "level"` )
+	})
+
+}
+
+func Test_decode_duration(t *testing.T) {
+
+	myTest("Decode durations", t, func() {
+		var d time.Duration
+		ShouldNotError( setValue(&d, "30s") )
+		ShouldEqual( d, 30 * time.Second )
+		ShouldNotError( setValue(&d, "1h30m") )
+		ShouldEqual( d, 90 * time.Minute )
+		ShouldNotError( setValue(&d, "2d") )
+		ShouldEqual( d, 48 * time.Hour )
+		ShouldNotError( setValue(&d, "1w1.5d12h") )
+		ShouldEqual( d, 216 * time.Hour )
+		ShouldNotError( setValue(&d, "-1d") )
+		ShouldEqual( d, -24 * time.Hour )
+		ShouldError( setValue(&d, "5M") )
+		ShouldError( setValue(&d, "5") )
+		ShouldEqual( typeName(reflect.TypeOf(d)), "duration" )
+	})
+
+	myTest("Durations in an option struct", t, func() {
+		var my struct{
+			Timeout		time.Duration	`default:"1m"`
+			Retry		[]time.Duration	`sep:","`
+		}
+		_,err := NewFromArgs([]string{arg0, "--retry=1s,2s,1d"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Timeout, time.Minute )
+		ShouldEqual( my.Retry, []time.Duration{time.Second, 2 * time.Second, 24 * time.Hour} )
+	})
+
+}
+
 // get more coverage by testing a few miscellaneous items
 func Test_decode_misc(t *testing.T) {
 
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"strings"
+	"reflect"
+)
+
+// EnvPrefix derives an environment variable for every option from the supplied
+// prefix and the option's gnu keyword.  For example, with the prefix "MYAPP"
+// the option --listen-addr may also be set with MYAPP_LISTEN_ADDR.  The
+// variable is read only when the option is absent from the command line.
+// Subcommands append their own name to the prefix (MYAPP_BUILD_OUTPUT).
+//
+// An environment variable may also be named for a single option with the env
+// key of a conventional struct tag:
+//
+//   Addr string `env:"LISTEN_ADDR"`
+//
+func EnvPrefix(prefix string) Setting {
+	return func(o *Option) {
+		o.envPrefix = envKey(prefix)
+	}
+}
+
+// return the environment variable name for an option, or a blank string if
+// there is none
+func (o *Option) envName(gnu_key string, attr reflect.StructTag) string {
+	if env,ok := attr.Lookup("env"); ok {
+		return env
+	}
+	if o.envPrefix == "" || gnu_key == "" {
+		return ""
+	}
+	return o.envPrefix + "_" + envKey(gnu_key)
+}
+
+// convert a keyword to upper case with underscores (listen-addr --> LISTEN_ADDR)
+func envKey(s string) string {
+	return toUpper(strings.Replace(s, "-", "_", -1))
+}
+
+// return the value of this option's environment variable, if set
+func (x opt) lookupEnv() (string, bool) {
+	if x.env == "" {
+		return "", false
+	}
+	return os.LookupEnv(x.env)
+}
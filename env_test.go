@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+)
+
+func TestEnv( t *testing.T ) {
+
+	type mySt struct{
+		ListenAddr	string
+		Port		int		`default:"8080"`
+		Debug		bool
+		Token		string	`env:"API_TOKEN"`
+	}
+
+	t.Setenv("MYAPP_LISTEN_ADDR", "0.0.0.0")
+	t.Setenv("MYAPP_PORT", "9090")
+	t.Setenv("MYAPP_DEBUG", "yes")
+	t.Setenv("API_TOKEN", "42")
+
+    myTest("Given environment variables and no command line options", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0}, &my, EnvPrefix("myapp"))
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "0.0.0.0" )
+		ShouldEqual( my.Port, 9090 )
+		ShouldBeTrue( my.Debug )
+		ShouldEqual( my.Token, "42" )
+	})
+
+    myTest("Given the command line should take precedence", t, func() {
+		var my mySt
+		argv := []string{arg0, "--listen-addr=localhost", "-p", "80", "--debug=no"}
+		_,err := NewFromArgs(argv, &my, EnvPrefix("MYAPP"))
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "localhost" )
+		ShouldEqual( my.Port, 80 )
+		ShouldBeTrue( !my.Debug )
+	})
+
+    myTest("Given no prefix, only named variables are read", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.ListenAddr, "" )
+		ShouldEqual( my.Port, 8080 )
+		ShouldEqual( my.Token, "42" )
+	})
+
+    myTest("Given an invalid environment value", t, func() {
+		t.Setenv("MYAPP_PORT", "eighty")
+		var my mySt
+		_,err := NewFromArgs([]string{arg0}, &my, EnvPrefix("MYAPP"))
+		ShouldError( err, `strconv.ParseInt: parsing "eighty": invalid syntax "MYAPP_PORT"` )
+	})
+
+    myTest("Given a subcommand, the prefix includes the command name", t, func() {
+		t.Setenv("MYAPP_BUILD_OUTPUT", "bin")
+		var b struct{ Output string }
+		_,err := NewFromArgs([]string{arg0, "build"}, EnvPrefix("MYAPP"), NewCommand("build", &b))
+		ShouldNotError( err )
+		ShouldEqual( b.Output, "bin" )
+	})
+
+    myTest("Given help text, environment variables should be documented", t, func() {
+		var my struct{
+			Port	int		`opt:"p:port:number:Port to listen on" default:"8080"`
+		}
+		op,_ := NewFromArgs([]string{arg0}, &my, EnvPrefix("MYAPP"))
+		ShouldEqual( op.HelpString(),
+			"SYNOPSIS\n"+
+			"    mycommand [OPTION]\n\n"+
+			"OPTION\n"+
+			"    -p number, --port=number Port to listen on (default: 8080) (env:\n"+
+			"                             MYAPP_PORT)\n\n")
+	})
+
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"fmt"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrOverflow is wrapped by an InvalidValueError when a number does not fit
+	// the size of its field.
+	ErrOverflow = errors.New("Overflow")
+	// ErrInvalidBool is wrapped by an InvalidValueError when a bool value is not
+	// one of true, false, yes, no, on, off, 1 or 0.
+	ErrInvalidBool = errors.New("invalid value for bool")
+)
+
+// UnknownOptionError is returned when the command line contains keys that are
+// not defined in the option struct.
+type UnknownOptionError struct {
+	Keys			[]string
+}
+
+func (e *UnknownOptionError) Error() string {
+	return plural("Invalid command line option", e.Keys)
+}
+
+// UnknownCommandError is returned when subcommands are defined and the command
+// line names a subcommand that is not one of them.
+type UnknownCommandError struct {
+	Name			string
+}
+
+func (e *UnknownCommandError) Error() string {
+	return "Unknown command: (" + e.Name + ")"
+}
+
+// MissingOptionError is returned when required options were not supplied.
+// Each key is given as the unix and gnu keys of the option (-p/--port).
+type MissingOptionError struct {
+	Keys			[]string
+}
+
+func (e *MissingOptionError) Error() string {
+	return plural("Missing required option", e.Keys)
+}
+
+// MissingValueError is returned when an option that takes a value is followed
+// by another key, or is the last argument on the command line.
+type MissingValueError struct {
+	Key				string
+}
+
+func (e *MissingValueError) Error() string {
+	return "Missing value for option: (" + e.Key + ")"
+}
+
+// TooManyArgsError is returned when the number of command line arguments
+// exceeds the capacity of the argument slice or array.
+type TooManyArgsError struct {
+	Limit			int
+	Got				int
+}
+
+func (e *TooManyArgsError) Error() string {
+	return fmt.Sprintf("number of arguments supplied exceeds limit (%v)", e.Limit)
+}
+
+// InvalidValueError is returned when a value cannot be decoded into its field.
+// Key is the option key, environment variable or config key that supplied the
+// value, and is blank for a command line argument.  Err is the underlying
+// strconv, time or custom type error.
+type InvalidValueError struct {
+	Key				string
+	Value			string
+	Type			string
+	Err				error
+}
+
+func (e *InvalidValueError) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + ` "` + e.Key + `"`
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// DefinitionError is returned by New when the ValidateDefinition setting is
+// given and the option struct, argument slice or subcommands are not valid.
+// Problems lists every problem found.  Problems in a subcommand are prefixed
+// with the subcommand name.
+type DefinitionError struct {
+	Problems		[]string
+}
+
+func (e *DefinitionError) Error() string {
+	return "invalid option definition: " + strings.Join(e.Problems, "; ")
+}
+
+// return an InvalidValueError for a value that could not be decoded into v1
+func invalidValue(v1 reflect.Value, val string, err error) error {
+	return &InvalidValueError{Value: val, Type: v1.Type().String(), Err: err}
+}
+
+// set the key of an InvalidValueError
+func withKey(err error, key string) error {
+	var e *InvalidValueError
+	if errors.As(err, &e) {
+		e.Key = key
+	}
+	return err
+}
+
+// format a message followed by a list of keys, e.g. "Invalid options: (x, y)"
+func plural(msg string, keys []string) string {
+	if len(keys) > 1 {
+		msg += "s"
+	}
+	return msg + ": (" + strings.Join(keys, ", ") + ")"
+}
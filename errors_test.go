@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestErrors( t *testing.T ) {
+
+    myTest("Given undefined options", t, func() {
+		var my struct{ Answer int }
+		_,err := NewFromArgs([]string{arg0, "--more=beer", "-x"}, &my)
+		var e *UnknownOptionError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Keys, []string{"more", "x"} )
+	})
+
+    myTest("Given an unknown subcommand", t, func() {
+		var my struct{ Answer int }
+		_,err := NewFromArgs([]string{arg0, "biuld"}, &my, NewCommand("build"))
+		var e *UnknownCommandError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Name, "biuld" )
+	})
+
+    myTest("Given missing required options", t, func() {
+		var my struct{ Answer int `required:"true"` }
+		_,err := NewFromArgs([]string{arg0}, &my)
+		var e *MissingOptionError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Keys, []string{"-a/--answer"} )
+	})
+
+    myTest("Given an option without its value", t, func() {
+		var my struct{ Count int; Verbose bool }
+		_,err := NewFromArgs([]string{arg0, "-c", "-v"}, &my)
+		var e *MissingValueError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Key, "c" )
+	})
+
+    myTest("Given too many arguments", t, func() {
+		var args [2]string
+		_,err := NewFromArgs([]string{arg0, "Arthur", "Towel", "Vogon"}, &args)
+		var e *TooManyArgsError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Limit, 2 )
+		ShouldEqual( e.Got, 3 )
+	})
+
+    myTest("Given an invalid option value", t, func() {
+		var my struct{ Answer int }
+		_,err := NewFromArgs([]string{arg0, "--answer=forty-two"}, &my)
+		var e *InvalidValueError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Key, "answer" )
+		ShouldEqual( e.Value, "forty-two" )
+		ShouldEqual( e.Type, "int" )
+		ShouldBeTrue( errors.Is(err, strconv.ErrSyntax) )
+		var ne *strconv.NumError
+		ShouldBeTrue( errors.As(err, &ne) )
+	})
+
+    myTest("Given values that overflow", t, func() {
+		var my struct{ Small int8 }
+		_,err := NewFromArgs([]string{arg0, "-s", "1024"}, &my)
+		ShouldBeTrue( errors.Is(err, ErrOverflow) )
+		ShouldError( err, `Overflow "s"` )
+		var args [1]int8
+		_,err = NewFromArgs([]string{arg0, "1024"}, &args)
+		ShouldBeTrue( errors.Is(err, ErrOverflow) )
+		ShouldError( err, "Overflow" )
+	})
+
+    myTest("Given an invalid bool from the environment", t, func() {
+		t.Setenv("MYAPP_DEBUG", "maybe")
+		var my struct{ Debug bool }
+		_,err := NewFromArgs([]string{arg0}, &my, EnvPrefix("MYAPP"))
+		var e *InvalidValueError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Key, "MYAPP_DEBUG" )
+		ShouldBeTrue( errors.Is(err, ErrInvalidBool) )
+	})
+
+    myTest("Given invalid definitions in validation mode", t, func() {
+		var my struct{
+			Answer	int		`a:A flag`
+			Again	int		`a:A flag`
+			pvt		string
+			Words	[][]string
+			Port	int		`default:"eighty"`
+			Ask		string	`ask:a:Ask a question`
+		}
+		var args []string
+		var sub struct{ Chan chan int }
+		_,err := NewFromArgs([]string{arg0}, &my, &args, ValidateDefinition(),
+			NewCommand("serve", &sub))
+		var e *DefinitionError
+		ShouldBeTrue( errors.As(err, &e) )
+		ShouldEqual( e.Problems, []string{
+			"key already used (a)",
+			"private field not allowed (pvt)",
+			"type [][]string not allowed (Words)",
+			`invalid default value (Port): strconv.ParseInt: parsing "eighty": invalid syntax`,
+			"unix style flag should be a single character (Ask)",
+			"key already used (a)",
+			"serve: type chan not allowed (Chan)",
+		})
+	})
+
+    myTest("Given invalid arguments to New in validation mode", t, func() {
+		var my struct{ Answer int }
+		var args []string
+		var n int
+		_,err := NewFromArgs([]string{arg0}, &args, &my, ValidateDefinition())
+		ShouldError( err, "invalid option definition: first argument cannot be a slice; "+
+			"second argument should not be a struct pointer" )
+		_,err = NewFromArgs([]string{arg0}, my, &n, ValidateDefinition())
+		ShouldError( err, "invalid option definition: expected struct or slice pointer; "+
+			"expected struct or slice pointer" )
+		_,err = NewFromArgs([]string{arg0}, ValidateDefinition())
+		ShouldError( err, "invalid option definition: expected one or two arguments" )
+		_,err = NewFromArgs([]string{arg0}, &my, ValidateDefinition(),
+			NewCommand("serve"), NewCommand("serve"))
+		ShouldError( err, "invalid option definition: command already defined (serve)" )
+		_,err = NewFromArgs([]string{arg0}, &my, ValidateDefinition(),
+			NewCommand("-x"), NewCommand(""))
+		ShouldError( err, "invalid option definition: invalid command name (-x); "+
+			"invalid command name ()" )
+	})
+
+}
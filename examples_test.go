@@ -50,6 +50,36 @@ func ExampleNew() {
 	// 1 Towel
 }
 
+// An argument vector may be supplied instead of reading os.Args
+func ExampleNewFromArgs() {
+
+	// Define options
+	var opt struct{
+		Answer		int
+		Babel		bool
+	}
+
+	// Define argument slice
+	var args []string
+
+	// Parse the supplied arguments
+	argv := []string{"mycommand", "--answer=42", "-b", "Towel"}
+	_, err := option.NewFromArgs(argv, &opt, &args)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	fmt.Printf("%d\n", opt.Answer)
+	fmt.Printf("%v\n", opt.Babel)
+	fmt.Printf("%v\n", args)
+
+	// Output:
+	// 42
+	// true
+	// [Towel]
+}
+
 // The following example shows a simple option definition
 func ExampleOption_Help() {
 
@@ -85,7 +115,7 @@ func ExampleOption_Help() {
 	// OPTIONS
 	//     -a int, --answer=int
 	//
-	//     -b, --babel
+	//     -b, --[no-]babel
 	//
 	//     -q string, --question=string
 }
@@ -120,12 +150,12 @@ func ExampleOption_Help_tags() {
 	//     mycommand [OPTIONS]
 	//
 	// OPTIONS
-	//     -I int      Supply your answer
+	//     -I int           Supply your answer
 	//
-	//     --translate Enable bable fish translator
+	//     --[no-]translate Enable bable fish translator
 	//
 	//     -a question, --ask=question
-	//                 Ask the ultimate question
+	//                      Ask the ultimate question
 }
 
 // Add sections to help text
@@ -185,16 +215,16 @@ func ExampleOption_Section() {
 	//     physicists accident with science.
 	//
 	// OPTIONS
-	//     -I int      Supply your answer
+	//     -I int           Supply your answer
 	//
 	// BABLE FISH TRANSLATOR
 	//     Babel Fish patterns exist else communication decode centers which killed
 	//     brainwave kidneys prove logic combining best refused.
 	//
-	//     --translate Enable bable fish translator
+	//     --[no-]translate Enable bable fish translator
 	//
 	//     -a question, --ask=question
-	//                 Ask the ultimate question
+	//                      Ask the ultimate question
 	//
 	// NOTES
 	//     Stolen whim bizarrely speech have evolved small zebra supplied coincidence
@@ -5,9 +5,10 @@
 package option
 
 import (
-	"os"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -16,14 +17,69 @@ const (
 	help_width	= 79
 )
 
-var (
-	indent1_str string
-	indent2_str string
-)
+// help text layout
+type layout struct {
+	width			int					// total width of each line
+	indent			int					// indent of items and paragraphs
+	column			int					// column of the option help text
+}
+
+// HelpWidth sets the width of the help text.  By default the help text is as
+// wide as the terminal when standard output is a terminal, and 79 columns
+// otherwise.
+func HelpWidth(width int) Setting {
+	return func(o *Option) {
+		o.helpWidth = width
+	}
+}
 
-func init () {
-	indent1_str = strings.Repeat(" ", indent1)
-	indent2_str = strings.Repeat(" ", indent2)
+// HelpIndent sets the indent of options and paragraphs in the help text (4 by
+// default), and the column at which the help text of each option begins.  An
+// option key that reaches the column is followed by a line break.
+//
+// By default, or with a column of 0, the help text is lined up after the
+// longest option key, at column 16 or more, provided that key is no more than a
+// third of the width of the help text.
+func HelpIndent(indent, column int) Setting {
+	return func(o *Option) {
+		o.helpIndent = indent
+		o.helpColumn = column
+	}
+}
+
+// return the layout of the help text
+func (o *Option) layout() layout {
+	l := layout{help_width, indent1, indent2}
+	if o.helpWidth > 0 {
+		l.width = o.helpWidth
+	} else if w,ok := terminalWidth(); ok {
+		l.width = w
+	}
+	if o.helpIndent > 0 {
+		l.indent = o.helpIndent
+	}
+	if o.helpColumn > 0 {
+		l.column = o.helpColumn
+		return l
+	}
+	// line up the help text after the longest key that is not too long
+	var items []string
+	for _,v := range o.help {
+		if v.opt_ptr != nil && v.opt_ptr.helpText() != "" {
+			items = append(items, strings.Join(v.opt_ptr.keyForms(), ", "))
+		}
+	}
+	for _,s := range o.subs {
+		if s.text != "" {
+			items = append(items, s.name)
+		}
+	}
+	for _,item := range items {
+		if w := textWidth(item); w <= l.width/3 && l.indent + w + 1 > l.column {
+			l.column = l.indent + w + 1
+		}
+	}
+	return l
 }
 
 // Print the entire help text for this option configuration.
@@ -34,29 +90,37 @@ func (o *Option) Help() {
 // Return the entire help text for this option configuration as a string.
 func (o *Option) HelpString() string {
 	var str string
+	l := o.layout()
 	if _,ok := o.dochead["SYNOPSIS"]; !ok {
 		o.dochead["SYNOPSIS"] = []string{o.usageString()}
 	}
 	for _,heading := range []string{"NAME","SYNOPSIS","DESCRIPTION"} {
 		if paragraph,ok := o.dochead[heading]; ok {
-			str += sectionString(heading, paragraph) + "\n"
+			str += l.sectionString(heading, paragraph) + "\n"
 		}
 	}
+	str += o.commandsString(l)
 	var last_type int8 = -1
-	for i,v := range o.help {
+	for _,v := range o.help {
 		if v.typ == typ_sect {
-			str += sectionString(v.heading, v.paragraph)
+			str += l.sectionString(v.heading, v.paragraph)
+		} else if v.typ == typ_group {
+			// option group heading
+			if last_type == -1 {
+				str += o.optionsHeading() + "\n"
+			}
+			if last_type == typ_sect || last_type == -1 {
+				str += "\n"
+			}
+			str += wrap(v.heading, l.width) + "\n"
 		} else {
 			if last_type == -1 {
-				str += "OPTION"
-				if o.opt_count > 1 {
-					str += "S"
-				}
+				str += o.optionsHeading()
 			}
-			if last_type != typ_flag && last_type != typ_option {
+			if last_type != typ_flag && last_type != typ_option && last_type != typ_group {
 				str += "\n"
 			}
-			str += o.optionString(i,v)
+			str += o.optionString(l, v)
 			// double line space
 			str += "\n"
 		}
@@ -65,6 +129,14 @@ func (o *Option) HelpString() string {
 	return strings.TrimRight(str,"\n")+"\n\n"
 }
 
+// return the heading written before the first option or option group
+func (o *Option) optionsHeading() string {
+	if o.opt_count > 1 {
+		return "OPTIONS"
+	}
+	return "OPTION"
+}
+
 // Add section heading and paragraphs to your help text
 func (o *Option) Section(heading string, paragraph ...string) {
 	var zero_ptr *opt
@@ -77,7 +149,7 @@ func (o *Option) Section(heading string, paragraph ...string) {
 		key := r[0]
 		heading = r[1]
 		for i,v := range o.help {
-			if v.opt_ptr.u_key == key || v.opt_ptr.gnu_key == key {
+			if v.opt_ptr != nil && (v.opt_ptr.u_key == key || v.opt_ptr.gnu_key == key) {
 				found = true
 				o.help = insert(o.help, hp{zero_ptr, heading, paragraph, typ_sect}, i)
 				break
@@ -89,38 +161,92 @@ func (o *Option) Section(heading string, paragraph ...string) {
 	}
 }
 
-func (o *Option) optionString (i int, v hp) string {
-	var text string
-	text += indent1_str
-	if v.opt_ptr.u_key != "" {
-		text += "-" + v.opt_ptr.u_key
-		if v.opt_ptr.placeholder != "" && v.opt_ptr.placeholder != "bool" {
-			text += " "+v.opt_ptr.placeholder
+func (o *Option) optionString (l layout, v hp) string {
+	text := strings.Join(v.opt_ptr.keyForms(), ", ")
+	return l.itemString(text, v.opt_ptr.helpText())
+}
+
+// return each key of an option with its placeholder (-p number, --port=number)
+func (x opt) keyForms() []string {
+	var forms []string
+	// repeatable options are marked with an ellipsis
+	more := ""
+	if x.repeatable() {
+		more = "..."
+	}
+	ph := ""
+	if x.placeholder != "" && x.placeholder != "bool" {
+		ph = x.placeholder
+	}
+	if x.u_key != "" {
+		text := "-" + x.u_key
+		if ph != "" {
+			text += " " + ph
 		}
+		forms = append(forms, text + more)
 	}
-	if v.opt_ptr.gnu_key != "" {
-		if v.opt_ptr.u_key != "" {
-			text += ", "
+	if x.gnu_key != "" {
+		text := "--" + x.gnu_key
+		if x.negatable() {
+			text = "--[no-]" + x.gnu_key
 		}
-		text += "--" + v.opt_ptr.gnu_key
-		if v.opt_ptr.placeholder != "" && v.opt_ptr.placeholder != "bool" {
-			text += "="+v.opt_ptr.placeholder
+		if ph != "" {
+			text += "=" + ph
 		}
+		forms = append(forms, text + more)
 	}
+	return forms
+}
+
+// return the help text of an option followed by its default value, environment
+// variable and whether it is required
+func (x opt) helpText() string {
+	help_text := x.text
+	if d,ok := x.attr.Lookup("default"); ok {
+		help_text = strings.TrimSpace(help_text + " (default: "+d+")")
+	}
+	if x.env != "" {
+		help_text = strings.TrimSpace(help_text + " (env: "+x.env+")")
+	}
+	if x.required() {
+		help_text = strings.TrimSpace(help_text + " (required)")
+	}
+	return help_text
+}
+
+// format an indented item followed by its wrapped help text
+func (l layout) itemString (item, help_text string) string {
+	text := strings.Repeat(" ", l.indent) + item
+	column := strings.Repeat(" ", l.column)
 	spc := ""
-	if help_text := v.opt_ptr.text; help_text != "" {
-		if len(text) >= indent2 {
-			spc = "\n" + indent2_str
+	if help_text != "" {
+		if textWidth(text) >= l.column {
+			spc = "\n" + column
 		} else {
-			spc = strings.Repeat(" ", indent2 - len(text))
+			spc = strings.Repeat(" ", l.column - textWidth(text))
 		}
-		help_text = wrap(help_text, help_width - indent2)
-		help_text = strings.Replace(help_text, "\n", "\n"+indent2_str, -1)
+		help_text = indentLines(wrap(help_text, l.width - l.column), column)
 		text += spc+help_text
 	}
 	return text+"\n"
 }
 
+// list the available subcommands
+func (o *Option) commandsString (l layout) string {
+	if len(o.subs) == 0 {
+		return ""
+	}
+	str := "COMMAND"
+	if len(o.subs) > 1 {
+		str += "S"
+	}
+	str += "\n"
+	for _,s := range o.subs {
+		str += l.itemString(s.name, s.text)
+	}
+	return str + "\n"
+}
+
 func insert (src []hp, h hp, i int) []hp {
 	tmp := append(src, h)
 	copy(tmp[i+1:], tmp[i:])
@@ -128,9 +254,10 @@ func insert (src []hp, h hp, i int) []hp {
 	return tmp
 }
 
-func sectionString(heading string, pa []string) string {
+func (l layout) sectionString(heading string, pa []string) string {
+	indent := strings.Repeat(" ", l.indent)
 	if heading != "" {
-		heading = wrap(heading, help_width) + "\n"
+		heading = wrap(heading, l.width) + "\n"
 	}
 	if len(pa) == 0 {
 		return heading
@@ -141,24 +268,32 @@ func sectionString(heading string, pa []string) string {
 		//heading += "\n"
 	}
 	for _,p := range pa {
-		p = indent1_str + wrap(p, help_width - indent1)
-		paragraphs += strings.Replace(p, "\n", "\n"+indent1_str, -1)
+		paragraphs += indent + indentLines(wrap(p, l.width - l.indent), indent)
 		paragraphs += "\n\n"
 	}
 	if l := len(paragraphs); l > 0 {
 		paragraphs = paragraphs[:l-1]
 	}
 	// cleanup
-	paragraphs = strings.Replace(paragraphs,"\n"+indent1_str+"\n","\n\n",-1)
+	paragraphs = strings.Replace(paragraphs,"\n"+indent+"\n","\n\n",-1)
 	return heading + paragraphs
 }
 
 // Print the usage text for this command
 func (o *Option) Usage() {
 	usage := o.usageString()
+	if len(o.subs) > 0 {
+		var names []string
+		for _,s := range o.subs {
+			names = append(names, s.name)
+		}
+		usage += "\nCommands: " + strings.Join(names, ", ")
+	}
 	for _,v := range o.help {
 		h := ""
 		switch {
+		case v.opt_ptr == nil:
+			continue
 		case v.opt_ptr.gnu_key == "help":
 			h = "--help"
 		case v.opt_ptr.u_key == "h":
@@ -176,12 +311,23 @@ func (o *Option) Usage() {
 
 func (o *Option) usageString() string {
 	usage := o.cmd
-	if o.opt_count == 1 {
+	// required options are shown without brackets
+	optional := o.opt_count
+	for _,x := range o.optionList {
+		if x.required() {
+			usage += " " + x.usageString()
+			optional--
+		}
+	}
+	if optional == 1 {
 		usage += " [OPTION]"
 	}
-	if o.opt_count > 1 {
+	if optional > 1 {
 		usage += " [OPTIONS]"
 	}
+	if len(o.subs) > 0 {
+		return usage + " COMMAND"
+	}
 	if !o.hasArgSlice {
 		return usage
 	}
@@ -228,9 +374,30 @@ func (o *Option) usageString() string {
 //	return usage + arg
 //}
 
+// format a required option for the usage text (-p number or --port=number)
+func (x opt) usageString() string {
+	ph := ""
+	if x.placeholder != "" && x.placeholder != "bool" {
+		ph = x.placeholder
+	}
+	if x.u_key != "" {
+		if ph != "" {
+			ph = " " + ph
+		}
+		return "-" + x.u_key + ph
+	}
+	if ph != "" {
+		ph = "=" + ph
+	}
+	return "--" + x.gnu_key + ph
+}
+
 // Returns the filename of this executable without the path
-func getCmd() string {
-	cmd := os.Args[0]
+func getCmd(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	cmd := args[0]
 	i := strings.LastIndexAny(cmd,`/\`)
 	if i > -1 {
 		cmd = cmd[i+1:]
@@ -240,22 +407,90 @@ func getCmd() string {
 
 
 
-// wrap string at width
+// wrap string at width.  Explicit line breaks are kept, and lines that begin
+// with a space or tab are preformatted and left as they are.  Words longer than
+// the width are never split.
 func wrap (s string, width int) string {
-	line := ""
-	sl := width
-	sa := strings.Split(s," ")
-	for _,word := range sa {
-		if (len(word) + 1) > sl {
-			line += "\n" + word
-			sl = width - len(word)+1
-		} else {
-			sl -= len(word)+1
-			if line != "" {
-				line += " "
-			}
-			line += word
+	lines := strings.Split(s, "\n")
+	for i,line := range lines {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		lines[i] = wrapLine(line, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap a single line of text at width.  The last column is left blank, since
+// some terminals break the line after writing to it.  The spaces between words
+// on the same line are kept.
+func wrapLine (s string, width int) string {
+	str := ""
+	line_width := 0
+	gap := ""
+	for _,word := range splitWords(s) {
+		if word[0] == ' ' || word[0] == '\t' {
+			gap = word
+			continue
+		}
+		w := textWidth(word)
+		if line_width > 0 && line_width + len(gap) + w >= width {
+			str += "\n"
+			line_width = 0
+		}
+		if line_width > 0 {
+			str += gap
+			line_width += len(gap)
+		}
+		str += word
+		line_width += w
+		gap = ""
+	}
+	return str
+}
+
+// split a string into words and the runs of blanks between them.  Chinese and
+// Japanese are written without spaces, so each of their characters is a word.
+func splitWords (s string) []string {
+	var words []string
+	start := 0
+	for i,r := range s {
+		if i > start && (isIdeograph(r) || isIdeograph(lastRune(s[start:i])) ||
+			isBlank(s[i]) != isBlank(s[start])) {
+			words = append(words, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// return true if a rune is a Chinese or Japanese character, or their
+// punctuation
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60)
+}
+
+// return the last rune of a string
+func lastRune(s string) rune {
+	r,_ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// indent each line of a string except the first, leaving blank lines empty
+func indentLines (s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
 		}
 	}
-	return line
+	return strings.Join(lines, "\n")
 }
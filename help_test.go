@@ -6,6 +6,8 @@ package option
 
 import (
 	"fmt"
+	"net"
+	"time"
 	"testing"
 )
 
@@ -17,6 +19,8 @@ type x_tst struct {
 
 func init() {
 	fmt.Print("")
+	// the help text is expected at the default width on any terminal
+	terminalWidth = func() (int, bool) { return 0, false }
 }
 
 func TestUsage( t *testing.T ) {
@@ -169,10 +173,176 @@ func TestHelp( t *testing.T ) {
 			expected := "SYNOPSIS\n"+
 			"    mycommand [OPTION]\n\n"+
 			"OPTION\n"+
-			"    -A string, --ask=string\n"+
-			"                Ask a question\n\n"
+			"    -A string, --ask=string Ask a question\n\n"
 			ShouldEqual(result, expected)
 	})
+	myTest("Given option struct with conventional tag and default", t, func() {
+			var myops struct{
+				Port  int		`opt:"p:port:number:Port to listen on" default:"8080"`
+				Host  string	`default:"localhost"`
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"    -p number, --port=number Port to listen on (default: 8080)\n\n"+
+			"    -h string, --host=string (default: localhost)\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with colon tag and quoted help text", t, func() {
+			var myops struct{
+				Qt  string		`q:"quoted"`
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTION]\n\n"+
+			"OPTION\n"+
+			"    -q string   \"quoted\"\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with repeatable options", t, func() {
+			var myops struct{
+				Include	[]string	`Add a directory to the search path`
+				Verbose	[]bool
+				Label	map[string]string
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"    -i string..., --include=string...\n"+
+			"                Add a directory to the search path\n\n"+
+			"    -v..., --[no-]verbose...\n\n"+
+			"    -l key=string..., --label=key=string...\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with option groups", t, func() {
+			type Logging struct{
+				LogLevel	string	`Minimum level to log`
+			}
+			var myops struct{
+				Verbose		bool
+				Logging
+				DB			struct{ Host string }	`Database options`
+			}
+			op,_ := New(&myops)
+			op.Section("NOTES", "Towel")
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"    -v, --[no-]verbose\n\n"+
+			"LOGGING\n"+
+			"    -l string, --log-level=string\n"+
+			"                Minimum level to log\n\n"+
+			"Database options\n"+
+			"    --db-host=string\n\n"+
+			"NOTES\n"+
+			"    Towel\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with only an option group", t, func() {
+			var myops struct{
+				DB			struct{ Host string }	`Database options`
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTION]\n\n"+
+			"OPTION\n\n"+
+			"Database options\n"+
+			"    --db-host=string\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with custom types", t, func() {
+			var myops struct{
+				Level	level	`Logging level`
+				Addr	net.IP
+				Timeout	time.Duration
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"    -l low|high, --level=low|high\n"+
+			"                Logging level\n\n"+
+			"    -a ip, --addr=ip\n\n"+
+			"    -t duration, --timeout=duration\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given option struct with required options", t, func() {
+			var myops struct{
+				Port	int		`opt:"p:port:number:Port to listen on" required:"true"`
+				Host	string	`opt:":host:" required:"true"`
+				Debug	bool
+			}
+			op,_ := New(&myops)
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"    mycommand -p number --host=string [OPTION]\n\n"+
+			"OPTIONS\n"+
+			"    -p number, --port=number Port to listen on (required)\n\n"+
+			"    --host=string            (required)\n\n"+
+			"    -d, --[no-]debug\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given a help width and indent", t, func() {
+			var myops struct{
+				Port	int		`opt:"p:port:number:Port to listen on, with a description that wraps"`
+				Addr	string	`opt:"a:listen-address:host-and-port:Listen on this address"`
+				Debug	bool	`opt:"Debug mode"`
+			}
+			op,_ := NewFromArgs([]string{"mycommand"}, &myops, HelpWidth(75), HelpIndent(2, 0))
+			op.Section("NOTES", "A paragraph that is long enough to wrap at seventy five columns, "+
+				"but not at eighty.")
+			result := op.HelpString()
+			expected := "SYNOPSIS\n"+
+			"  mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"  -p number, --port=number Port to listen on, with a description that\n"+
+			"                           wraps\n\n"+
+			"  -a host-and-port, --listen-address=host-and-port\n"+
+			"                           Listen on this address\n\n"+
+			"  -d, --[no-]debug         Debug mode\n\n"+
+			"NOTES\n"+
+			"  A paragraph that is long enough to wrap at seventy five columns, but not\n"+
+			"  at eighty.\n\n"
+			ShouldEqual(result, expected)
+			op,_ = NewFromArgs([]string{"mycommand"}, &myops, HelpIndent(2, 14))
+			result = op.HelpString()
+			expected = "SYNOPSIS\n"+
+			"  mycommand [OPTIONS]\n\n"+
+			"OPTIONS\n"+
+			"  -p number, --port=number\n"+
+			"              Port to listen on, with a description that wraps\n\n"+
+			"  -a host-and-port, --listen-address=host-and-port\n"+
+			"              Listen on this address\n\n"+
+			"  -d, --[no-]debug\n"+
+			"              Debug mode\n\n"
+			ShouldEqual(result, expected)
+	})
+	myTest("Given a terminal width", t, func() {
+			defer func(fn func() (int, bool)) { terminalWidth = fn }(terminalWidth)
+			terminalWidth = func() (int, bool) { return 60, true }
+			var myops struct{ Debug bool }
+			op,_ := New(&myops)
+			ShouldEqual( op.layout().width, 60 )
+			op,_ = New(&myops, HelpWidth(100))
+			ShouldEqual( op.layout().width, 100 )
+	})
+	myTest("Given the COLUMNS variable", t, func() {
+			t.Setenv("COLUMNS", "120")
+			w,ok := columnsEnv()
+			ShouldBeTrue( ok )
+			ShouldEqual( w, 120 )
+			t.Setenv("COLUMNS", "wide")
+			_,ok = columnsEnv()
+			ShouldBeTrue( !ok )
+	})
 	myTest("Given duplicate gnu keys", t, func() {
 			var myops struct{
 				A  string		`ask:Ask a question`
@@ -298,3 +468,51 @@ func TestSection( t *testing.T ) {
 	resetArgs()
 }
 
+
+func TestWrap( t *testing.T ) {
+
+    myTest("Given text to wrap", t, func() {
+		for _,tst := range []struct{ s string; width int; expected string }{
+			{"Supercalifragilistic is long", 10, "Supercalifragilistic\nis long"},
+			{"See https://example.com/a/very/long/path for more", 20,
+				"See\nhttps://example.com/a/very/long/path\nfor more"},
+			{"One sentence.  Another sentence.", 40, "One sentence.  Another sentence."},
+			{"One sentence.  Another sentence.", 20, "One sentence.\nAnother sentence."},
+			{"Über große Straßen fahren", 16, "Über große\nStraßen fahren"},
+			{"日本語のテキスト 日本語のテキスト", 18, "日本語のテキスト\n日本語のテキスト"},
+			{"日本語の説明文です。", 11, "日本語の説\n明文です。"},
+			{"Launch 🚀 🚀 🚀 now", 13, "Launch 🚀 🚀\n🚀 now"},
+			{"First line\nsecond line is longer", 12, "First line\nsecond line\nis longer"},
+			{"Example:\n\n    mycmd --port=8080 --host=localhost\n", 20,
+				"Example:\n\n    mycmd --port=8080 --host=localhost\n"},
+		}{
+			ShouldEqual( wrap(tst.s, tst.width), tst.expected )
+		}
+	})
+
+    myTest("Given the display width of text", t, func() {
+		ShouldEqual( textWidth("abc"), 3 )
+		ShouldEqual( textWidth("Straße"), 6 )
+		ShouldEqual( textWidth("été"), 3 )
+		ShouldEqual( textWidth("日本"), 4 )
+		ShouldEqual( textWidth("ｈｉ"), 4 )
+		ShouldEqual( textWidth("🚀!"), 3 )
+	})
+
+    myTest("Given help text with line breaks and wide characters", t, func() {
+		var myops struct{
+			Name	string	`opt:"n:name:名前:Your name\n\n  e.g. 山田"`
+		}
+		op,_ := NewFromArgs([]string{"mycommand"}, &myops)
+		op.Section("例", "日本語の説明文です。日本語の説明文です。日本語の説明文です。日本語の説明文です。")
+		ShouldEqual( op.HelpString(), "SYNOPSIS\n"+
+		"    mycommand [OPTION]\n\n"+
+		"OPTION\n"+
+		"    -n 名前, --name=名前 Your name\n\n"+
+		"                           e.g. 山田\n\n"+
+		"例\n"+
+		"    日本語の説明文です。日本語の説明文です。日本語の説明文です。日本語の説明文\n"+
+		"    です。\n\n" )
+	})
+
+}
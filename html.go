@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"html"
+	"strings"
+)
+
+// Returns the help text as a standalone HTML document.  Sections become
+// headings, and the options and subcommands are listed as definition lists.
+// Like MarkdownString, the document is built from the same sections and
+// options as HelpString.
+func (o *Option) HTMLString() string {
+	str := "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n"
+	str += "<title>" + html.EscapeString(o.cmd) + "</title>\n"
+	str += "</head>\n<body>\n"
+	str += "<h1>" + html.EscapeString(o.cmd) + "</h1>\n"
+	for _,heading := range []string{"NAME","SYNOPSIS","DESCRIPTION"} {
+		paragraph,ok := o.dochead[heading]
+		if heading == "SYNOPSIS" && !ok {
+			paragraph,ok = []string{o.usageString()}, true
+		}
+		if !ok {
+			continue
+		}
+		if heading == "SYNOPSIS" {
+			str += "<h2>SYNOPSIS</h2>\n<pre>" + html.EscapeString(strings.Join(paragraph, "\n")) + "</pre>\n"
+			continue
+		}
+		str += htmlSection(heading, paragraph)
+	}
+	if len(o.subs) > 0 {
+		str += "<h2>COMMANDS</h2>\n<dl>\n"
+		for _,s := range o.subs {
+			str += htmlItem([]string{s.name}, s.text)
+		}
+		str += "</dl>\n"
+	}
+	var last_type int8 = -1
+	for _,v := range o.help {
+		if v.typ != typ_option && last_type == typ_option {
+			str += "</dl>\n"
+		}
+		switch v.typ {
+		case typ_sect:
+			str += htmlSection(v.heading, v.paragraph)
+		case typ_group:
+			if last_type == -1 {
+				str += "<h2>" + o.optionsHeading() + "</h2>\n"
+			}
+			str += "<h3>" + html.EscapeString(v.heading) + "</h3>\n"
+		default:
+			if last_type == -1 {
+				str += "<h2>" + o.optionsHeading() + "</h2>\n"
+			}
+			if last_type != typ_option {
+				str += "<dl>\n"
+			}
+			str += htmlItem(v.opt_ptr.keyForms(), v.opt_ptr.helpText())
+		}
+		last_type = v.typ
+	}
+	if last_type == typ_option {
+		str += "</dl>\n"
+	}
+	return str + "</body>\n</html>\n"
+}
+
+// format a section heading and its paragraphs
+func htmlSection(heading string, pa []string) string {
+	str := ""
+	if heading != "" {
+		str = "<h2>" + html.EscapeString(heading) + "</h2>\n"
+	}
+	for _,p := range pa {
+		str += "<p>" + htmlText(p) + "</p>\n"
+	}
+	return str
+}
+
+// format a term of a definition list with its description
+func htmlItem(terms []string, text string) string {
+	for i,t := range terms {
+		terms[i] = "<code>" + html.EscapeString(t) + "</code>"
+	}
+	str := "<dt>" + strings.Join(terms, ", ") + "</dt>\n"
+	if text != "" {
+		str += "<dd>" + htmlText(text) + "</dd>\n"
+	}
+	return str
+}
+
+// escape text and keep its explicit line breaks
+func htmlText(s string) string {
+	return strings.Replace(html.EscapeString(s), "\n", "<br>\n", -1)
+}
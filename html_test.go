@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+)
+
+func TestHTML( t *testing.T ) {
+
+    myTest("Given options, sections and a subcommand", t, func() {
+		var my docSt
+		var files []string
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, NewCommand("build:Compile it\nquickly", &files))
+		ShouldNotError( err )
+		op.Section("DESCRIPTION", "Serves <things> & more.")
+		op.Section("debug:DEBUGGING", "Do not use in production.")
+		ShouldEqual( op.HTMLString(), `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>mycmd</title>
+</head>
+<body>
+<h1>mycmd</h1>
+<h2>SYNOPSIS</h2>
+<pre>mycmd [OPTIONS] COMMAND</pre>
+<h2>DESCRIPTION</h2>
+<p>Serves &lt;things&gt; &amp; more.</p>
+<h2>COMMANDS</h2>
+<dl>
+<dt><code>build</code></dt>
+<dd>Compile it<br>
+quickly</dd>
+</dl>
+<h2>OPTIONS</h2>
+<dl>
+<dt><code>-p number</code>, <code>--port=number</code></dt>
+<dd>Port to listen on (default: 8080)</dd>
+<dt><code>-l low|high</code>, <code>--level=low|high</code></dt>
+<dd>Log &lt;level&gt;</dd>
+</dl>
+<h2>DEBUGGING</h2>
+<p>Do not use in production.</p>
+<dl>
+<dt><code>-d</code>, <code>--[no-]debug</code></dt>
+</dl>
+</body>
+</html>
+`)
+	})
+
+}
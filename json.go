@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"reflect"
+	"encoding/json"
+)
+
+// JSON description of a command
+type jsonCommand struct {
+	Name			string				`json:"name"`
+	Text			string				`json:"text,omitempty"`
+	Synopsis		string				`json:"synopsis"`
+	Options			[]jsonOption		`json:"options"`
+	Arguments		jsonArguments		`json:"arguments"`
+	Sections		[]jsonSection		`json:"sections"`
+	Commands		[]jsonCommand		`json:"commands"`
+}
+
+type jsonOption struct {
+	Field			string				`json:"field"`
+	UnixKey			string				`json:"unix_key,omitempty"`
+	GnuKey			string				`json:"gnu_key,omitempty"`
+	Type			string				`json:"type"`
+	Placeholder		string				`json:"placeholder,omitempty"`
+	Default			*string				`json:"default,omitempty"`
+	Env				string				`json:"env,omitempty"`
+	Enum			[]string			`json:"enum,omitempty"`
+	Help			string				`json:"help,omitempty"`
+	Group			string				`json:"group,omitempty"`
+	Required		bool				`json:"required"`
+	Repeatable		bool				`json:"repeatable"`
+	Negatable		bool				`json:"negatable"`	// accepts --no-key
+}
+
+type jsonArguments struct {
+	Accepted		bool				`json:"accepted"`
+	Min				int					`json:"min"`
+	Max				*int				`json:"max"`		// null if unlimited
+}
+
+type jsonSection struct {
+	Heading			string				`json:"heading"`
+	Paragraphs		[]string			`json:"paragraphs"`
+	Before			string				`json:"before,omitempty"`	// key of the following option
+}
+
+// Returns a JSON description of the command line interface: the options with
+// their keys, types, placeholders, defaults and help text, the help sections,
+// the number of arguments accepted, and the subcommands.  This may be read by
+// other tools, such as a form to launch the program or a compatibility check
+// between versions.
+//
+// A section placed before an option with Section("key:HEADING") names the key
+// of that option in its before field.  The max field of the arguments is null
+// when their number is unlimited.
+func (o *Option) JSONString() string {
+	b, _ := json.MarshalIndent(o.jsonCommand(), "", "\t")
+	return string(b) + "\n"
+}
+
+// build the JSON description of this command and its subcommands
+func (o *Option) jsonCommand() jsonCommand {
+	c := jsonCommand{
+		Name:		o.cmd,
+		Text:		o.text,
+		Synopsis:	o.usageString(),
+		Options:	[]jsonOption{},
+		Sections:	[]jsonSection{},
+		Commands:	[]jsonCommand{},
+	}
+	if s,ok := o.dochead["SYNOPSIS"]; ok && len(s) > 0 {
+		c.Synopsis = s[0]
+	}
+	for _,heading := range []string{"NAME","DESCRIPTION"} {
+		if paragraph,ok := o.dochead[heading]; ok {
+			c.Sections = append(c.Sections, jsonSection{Heading: heading, Paragraphs: paragraph})
+		}
+	}
+	group := ""
+	var sect *jsonSection
+	for _,v := range o.help {
+		switch v.typ {
+		case typ_sect:
+			c.Sections = append(c.Sections, jsonSection{Heading: v.heading, Paragraphs: v.paragraph})
+			sect = &c.Sections[len(c.Sections)-1]
+			continue
+		case typ_group:
+			group = v.heading
+		default:
+			x := v.opt_ptr
+			if sect != nil && sect.Before == "" {
+				sect.Before = x.gnu_key
+				if sect.Before == "" {
+					sect.Before = x.u_key
+				}
+			}
+			jo := jsonOption{
+				Field:			x.name,
+				UnixKey:		x.u_key,
+				GnuKey:			x.gnu_key,
+				Type:			x.fld.Type().String(),
+				Placeholder:	x.placeholder,
+				Env:			x.env,
+				Help:			x.text,
+				Group:			group,
+				Required:		x.required(),
+				Repeatable:		x.repeatable(),
+				Negatable:		x.negatable(),
+			}
+			if d,ok := x.attr.Lookup("default"); ok {
+				jo.Default = &d
+			}
+			if x.attr.Get("enum") != "" {
+				jo.Enum = x.choices()
+			}
+			c.Options = append(c.Options, jo)
+		}
+		sect = nil
+	}
+	max := o.argSliceCap
+	c.Arguments.Max = &max
+	if o.hasArgSlice {
+		c.Arguments.Accepted = true
+		if o.argSliceCap == slice_limit {
+			c.Arguments.Max = nil
+		}
+		if o.argSliceRef.Kind() == reflect.Array {
+			c.Arguments.Min = o.argSliceCap
+		}
+	}
+	for _,s := range o.subs {
+		sc := s.jsonCommand()
+		sc.Name = s.name
+		c.Commands = append(c.Commands, sc)
+	}
+	return c
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+	"encoding/json"
+)
+
+func TestJSONString( t *testing.T ) {
+
+	type dbSt struct{
+		Host		string	`opt:"Database host"`
+	}
+	type mySt struct{
+		Port		int		`opt:"p:port:number:Port to listen on" default:"8080" env:"PORT"`
+		Level		string	`enum:"debug,info" required:"true"`
+		Tags		[]string
+		DB			dbSt	`opt:"db:DATABASE"`
+	}
+
+    myTest("Given options, sections and a subcommand", t, func() {
+		var my mySt
+		var files [2]string
+		var rest []string
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, &files, NewCommand("build:Compile it", &rest))
+		ShouldError( err, "Missing required option: (-l/--level)" )
+		op.Section("DESCRIPTION", "Serves things.")
+		op.Section("level:LOGGING", "Logs go to stderr.")
+		op.Section("NOTES", "Towel")
+		var c jsonCommand
+		ShouldNotError( json.Unmarshal([]byte(op.JSONString()), &c) )
+		ShouldEqual( c.Name, "mycmd" )
+		ShouldEqual( c.Synopsis, "mycmd -l string [OPTIONS] COMMAND" )
+		ShouldEqual( len(c.Options), 4 )
+		ShouldEqual( *c.Options[0].Default, "8080" )
+		ShouldEqual( c.Options[0].Env, "PORT" )
+		ShouldEqual( c.Options[0].Type, "int" )
+		ShouldEqual( c.Options[1].Enum, []string{"debug","info"} )
+		ShouldBeTrue( c.Options[1].Required )
+		ShouldEqual( c.Options[1].Default, (*string)(nil) )
+		ShouldBeTrue( c.Options[2].Repeatable )
+		ShouldEqual( c.Options[2].Type, "[]string" )
+		ShouldEqual( c.Options[3].GnuKey, "db-host" )
+		ShouldEqual( c.Options[3].Group, "DATABASE" )
+		ShouldEqual( len(c.Sections), 3 )
+		ShouldEqual( c.Sections[0].Heading, "DESCRIPTION" )
+		ShouldEqual( c.Sections[1].Before, "level" )
+		ShouldEqual( c.Sections[2].Before, "" )
+		ShouldEqual( c.Arguments.Min, 2 )
+		ShouldEqual( *c.Arguments.Max, 2 )
+		ShouldEqual( len(c.Commands), 1 )
+		ShouldEqual( c.Commands[0].Name, "build" )
+		ShouldEqual( c.Commands[0].Text, "Compile it" )
+		ShouldBeTrue( c.Commands[0].Arguments.Accepted )
+		ShouldEqual( c.Commands[0].Arguments.Max, (*int)(nil) )
+	})
+
+}
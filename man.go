@@ -0,0 +1,142 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Returns the help text as a man page in troff format, using the man macro
+// package.  The page is built from the same sections and options as
+// HelpString, and may be generated at build time and installed with the
+// program.  A program that defines a bool Man option of its own might print the
+// page with
+//
+//   if opts.Man {
+//       fmt.Print(op.ManPage(1))
+//   }
+//
+// and then be run as mycmd --man > mycmd.1.
+//
+// The man page of a subcommand is named after the command and subcommand
+// (mycmd-build).
+func (o *Option) ManPage(section int) string {
+	title := strings.Replace(o.cmd, " ", "-", -1)
+	str := ".TH " + manQuote(toUpper(title)) + " " + strconv.Itoa(section) + "\n"
+	if name,ok := o.dochead["NAME"]; ok {
+		str += manSection("NAME", name)
+	} else {
+		text := title
+		if o.text != "" {
+			text += " - " + o.text
+		}
+		str += manSection("NAME", []string{text})
+	}
+	synopsis,ok := o.dochead["SYNOPSIS"]
+	if !ok {
+		synopsis = []string{o.usageString()}
+	}
+	str += manSection("SYNOPSIS", synopsis)
+	if description,ok := o.dochead["DESCRIPTION"]; ok {
+		str += manSection("DESCRIPTION", description)
+	}
+	if len(o.subs) > 0 {
+		str += ".SH COMMANDS\n"
+		for _,s := range o.subs {
+			str += ".TP\n.B " + manEscape(s.name) + "\n"
+			if s.text != "" {
+				str += manEscape(s.text) + "\n"
+			}
+		}
+	}
+	var last_type int8 = -1
+	for _,v := range o.help {
+		switch v.typ {
+		case typ_sect:
+			str += manSection(v.heading, v.paragraph)
+		case typ_group:
+			if last_type == -1 {
+				str += ".SH " + o.optionsHeading() + "\n"
+			}
+			str += ".SS " + manQuote(v.heading) + "\n"
+		default:
+			if last_type == -1 {
+				str += ".SH " + o.optionsHeading() + "\n"
+			}
+			str += ".TP\n" + v.opt_ptr.manKeys() + "\n"
+			if text := v.opt_ptr.helpText(); text != "" {
+				str += manEscape(text) + "\n"
+			}
+		}
+		last_type = v.typ
+	}
+	return str
+}
+
+// format a section heading and its paragraphs
+func manSection(heading string, pa []string) string {
+	str := ""
+	if heading != "" {
+		str = ".SH " + manQuote(heading) + "\n"
+	}
+	for i,p := range pa {
+		if i > 0 {
+			str += ".PP\n"
+		}
+		str += manEscape(p) + "\n"
+	}
+	return str
+}
+
+// format the keys of an option in bold with italic placeholders
+func (x opt) manKeys() string {
+	ph := ""
+	if x.placeholder != "" && x.placeholder != "bool" {
+		ph = `\fI` + manEscape(x.placeholder) + `\fR`
+	}
+	more := ""
+	if x.repeatable() {
+		more = "..."
+	}
+	var keys []string
+	if x.u_key != "" {
+		key := `\fB\-` + manEscape(x.u_key) + `\fR`
+		if ph != "" {
+			key += " " + ph
+		}
+		keys = append(keys, key + more)
+	}
+	if x.gnu_key != "" {
+		key := `\fB\-\-` + manEscape(x.gnu_key) + `\fR`
+		if x.negatable() {
+			key = `\fB\-\-\fR[\fBno\-\fR]\fB` + manEscape(x.gnu_key) + `\fR`
+		}
+		if ph != "" {
+			key += "=" + ph
+		}
+		keys = append(keys, key + more)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// escape text for troff.  Lines may not begin with a control character.
+func manEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i,line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escape and quote a macro argument
+func manQuote(s string) string {
+	s = strings.Replace(manEscape(s), `"`, `""`, -1)
+	return `"` + strings.Replace(s, "\n", " ", -1) + `"`
+}
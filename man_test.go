@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+)
+
+func TestManPage( t *testing.T ) {
+
+	type buildSt struct{
+		Output		string	`opt:"o:output:file:Output file"`
+	}
+	type mySt struct{
+		Port		int		`opt:"p:port:number:Port to listen on" default:"8080"`
+		Include		[]string `opt:"I:::Include a directory, e.g. -I ./lib"`
+		Debug		bool
+	}
+
+    myTest("Given options, sections and a subcommand", t, func() {
+		var my mySt
+		var build buildSt
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, NewCommand("build:Compile it", &build))
+		ShouldNotError( err )
+		op.Section("NAME", "mycmd - serve things")
+		op.Section("DESCRIPTION", "First paragraph.", ".Second paragraph with a \\ backslash.")
+		op.Section("debug:DEBUGGING", "Do not use in production.")
+		op.Section("NOTES", "Towel")
+		ShouldEqual( op.ManPage(1), `.TH "MYCMD" 1
+.SH "NAME"
+mycmd \- serve things
+.SH "SYNOPSIS"
+mycmd [OPTIONS] COMMAND
+.SH "DESCRIPTION"
+First paragraph.
+.PP
+\&.Second paragraph with a \e backslash.
+.SH COMMANDS
+.TP
+.B build
+Compile it
+.SH OPTIONS
+.TP
+\fB\-p\fR \fInumber\fR, \fB\-\-port\fR=\fInumber\fR
+Port to listen on (default: 8080)
+.TP
+\fB\-I\fR...
+Include a directory, e.g. \-I ./lib
+.SH "DEBUGGING"
+Do not use in production.
+.TP
+\fB\-d\fR, \fB\-\-\fR[\fBno\-\fR]\fBdebug\fR
+.SH "NOTES"
+Towel
+`)
+	})
+
+    myTest("Given the man page of a subcommand", t, func() {
+		var my mySt
+		var build buildSt
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, NewCommand("build:Compile it", &build))
+		ShouldNotError( err )
+		ShouldEqual( op.Command("build").ManPage(8), `.TH "MYCMD\-BUILD" 8
+.SH "NAME"
+mycmd\-build \- Compile it
+.SH "SYNOPSIS"
+mycmd build [OPTION]
+.SH OPTION
+.TP
+\fB\-o\fR \fIfile\fR, \fB\-\-output\fR=\fIfile\fR
+Output file
+`)
+	})
+
+    myTest("Given options that are all in a group", t, func() {
+		var my struct{
+			DB		struct{ Host string }	`Database options`
+		}
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( op.ManPage(1), `.TH "MYCMD" 1
+.SH "NAME"
+mycmd
+.SH "SYNOPSIS"
+mycmd [OPTION]
+.SH OPTION
+.SS "Database options"
+.TP
+\fB\-\-db\-host\fR=\fIstring\fR
+`)
+	})
+
+}
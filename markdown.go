@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"strings"
+)
+
+// Returns the help text as a Markdown document.  Sections become headings, and
+// the options and subcommands are listed in tables.  The document is built from
+// the same sections and options as HelpString, so published documentation
+// always matches the program.
+func (o *Option) MarkdownString() string {
+	str := "# " + mdEscape(o.cmd) + "\n\n"
+	for _,heading := range []string{"NAME","SYNOPSIS","DESCRIPTION"} {
+		paragraph,ok := o.dochead[heading]
+		if heading == "SYNOPSIS" && !ok {
+			paragraph,ok = []string{o.usageString()}, true
+		}
+		if !ok {
+			continue
+		}
+		if heading == "SYNOPSIS" {
+			// the synopsis is shown verbatim
+			str += "## SYNOPSIS\n\n```\n" + strings.Join(paragraph, "\n") + "\n```\n\n"
+			continue
+		}
+		str += mdSection(heading, paragraph)
+	}
+	if len(o.subs) > 0 {
+		str += "## COMMANDS\n\n| Command | Description |\n| --- | --- |\n"
+		for _,s := range o.subs {
+			str += "| `" + s.name + "` | " + mdCell(s.text) + " |\n"
+		}
+		str += "\n"
+	}
+	var last_type int8 = -1
+	for _,v := range o.help {
+		if v.typ != typ_option && last_type == typ_option {
+			// a table ends with a blank line
+			str += "\n"
+		}
+		switch v.typ {
+		case typ_sect:
+			str += mdSection(v.heading, v.paragraph)
+		case typ_group:
+			if last_type == -1 {
+				str += "## " + o.optionsHeading() + "\n\n"
+			}
+			str += "### " + mdEscape(v.heading) + "\n\n"
+		default:
+			if last_type == -1 {
+				str += "## " + o.optionsHeading() + "\n\n"
+			}
+			if last_type != typ_option {
+				// start a new table
+				str += "| Option | Description |\n| --- | --- |\n"
+			}
+			var keys []string
+			for _,k := range v.opt_ptr.keyForms() {
+				// a bar must be escaped even in a code span
+				keys = append(keys, "`" + strings.Replace(k, "|", `\|`, -1) + "`")
+			}
+			str += "| " + strings.Join(keys, ", ") + " | " + mdCell(v.opt_ptr.helpText()) + " |\n"
+		}
+		last_type = v.typ
+	}
+	return strings.TrimRight(str, "\n") + "\n"
+}
+
+// format a section heading and its paragraphs
+func mdSection(heading string, pa []string) string {
+	str := ""
+	if heading != "" {
+		str = "## " + mdEscape(heading) + "\n\n"
+	}
+	for _,p := range pa {
+		str += mdEscape(p) + "\n\n"
+	}
+	return str
+}
+
+// escape the characters that would be read as Markdown formatting
+func mdEscape(s string) string {
+	for _,c := range []string{`\`, "`", "*", "_", "<", ">", "[", "]", "|"} {
+		s = strings.Replace(s, c, `\`+c, -1)
+	}
+	return s
+}
+
+// escape text for a table cell, which must be on a single line
+func mdCell(s string) string {
+	return strings.Replace(mdEscape(s), "\n", "<br>", -1)
+}
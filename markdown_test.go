@@ -0,0 +1,41 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"testing"
+)
+
+type docSt struct{
+	Port		int		`opt:"p:port:number:Port to listen on" default:"8080"`
+	Level		level	`opt:"Log <level>"`
+	Debug		bool
+}
+
+func TestMarkdown( t *testing.T ) {
+
+    myTest("Given options, sections and a subcommand", t, func() {
+		var my docSt
+		var files []string
+		op,err := NewFromArgs([]string{"path/mycmd"}, &my, NewCommand("build:Compile *all* of it", &files))
+		ShouldNotError( err )
+		op.Section("NAME", "mycmd - serve_things")
+		op.Section("debug:DEBUGGING", "Do not use in production.")
+		op.Section("NOTES", "Towel")
+		ShouldEqual( op.MarkdownString(), "# mycmd\n\n" +
+			"## NAME\n\nmycmd - serve\\_things\n\n" +
+			"## SYNOPSIS\n\n```\nmycmd [OPTIONS] COMMAND\n```\n\n" +
+			"## COMMANDS\n\n| Command | Description |\n| --- | --- |\n" +
+			"| `build` | Compile \\*all\\* of it |\n\n" +
+			"## OPTIONS\n\n| Option | Description |\n| --- | --- |\n" +
+			"| `-p number`, `--port=number` | Port to listen on (default: 8080) |\n" +
+			"| `-l low\\|high`, `--level=low\\|high` | Log \\<level\\> |\n\n" +
+			"## DEBUGGING\n\nDo not use in production.\n\n" +
+			"| Option | Description |\n| --- | --- |\n" +
+			"| `-d`, `--[no-]debug` |  |\n\n" +
+			"## NOTES\n\nTowel\n" )
+	})
+
+}
@@ -15,18 +15,53 @@
 //
 // All other command line arguments that are not defined in your option struct
 // will be interpreted as regular arguments and appended to your argument slice.
+// An argument of -- ends the options, and all arguments that follow it are
+// appended to the argument slice as they are, even if they begin with a dash.
+// A single dash is also an ordinary argument, usually meaning standard input.
+// Negative numbers such as -42 or -3.5 are values or arguments rather than keys,
+// unless digit keys are enabled with the DigitKeys setting.
+// A bool option with a gnu key may be set to false with --no-key, such as
+// --no-color, unless its tag includes negate:"false".
 // The number of arguments accepted by the parser may be limited by simply
 // making your argument slice with a maximum cap value.  If the user exceeds
 // this cap, an error will be returned.  Alternatively, a fixed array may be
 // defined.  This will cause the parser to expect an exact number of aguments,
 // or return an error.
 //
+// Subcommands in the style of git may be defined with NewCommand.  Each
+// subcommand has its own option struct and argument slice, and subcommands may
+// be nested to any depth.  Global options are accepted before the subcommand
+// name.  Unless there is an argument slice, a word that is not a subcommand name
+// returns an UnknownCommandError.  Only the invoked subcommand is parsed.
+//
+// Struct tags may also be written in the conventional key:"value" form, in
+// which case the colon separated key spec is given by the opt key.  A default
+// value may then be supplied with the default key:
+//
+//     Port int `opt:"p:port:number:Port to listen on" default:"8080"`
+//
+// Slice fields may be given more than once on the command line, and each value
+// is appended to the slice.  A sep key in the tag will also split each value
+// with the given separator, e.g. --tag=x,y,z with sep:",".  Map fields of
+// string keys accept key=value pairs in the same way.  A repeated key replaces
+// the earlier value, or is an error if the tag has unique:"true".
+//
+// Nested structs are option groups, each with its own heading in the help text.
+// An embedded struct is flattened into the enclosing struct, while the gnu keys
+// of a named struct field are prefixed with its keyword (--db-host, --db-port).
+//
+// An enum key in the tag limits an option to a comma separated list of values.
+// These values, and those of bool options, are suggested by the shell completion
+// scripts for bash, zsh and fish returned by Completion.  Values that are only
+// known at run time may be listed by a function registered with Completer.
+//
 package option
 
 import (
 	"os"
 	"fmt"
 	"errors"
+	"sort"
 	"regexp"
 	"strings"
 	"reflect"
@@ -39,6 +74,7 @@ const (
 	typ_sect 		int8 = 1
 	typ_flag 		int8 = 2
 	typ_option 		int8 = 3
+	typ_group		int8 = 4
 	typ_uoption		int8 = -3  // hack: undefined gnu-keyword option
 	slice_limit		int  = 32767
 )
@@ -70,12 +106,14 @@ type opt struct {
 	gnu_key			string				// gnu keyword
 	text			string				// help text
 	placeholder		string				// value placeholder
+	attr			reflect.StructTag	// conventional tag attributes (default, etc.)
+	env				string				// environment variable name
 }
 
 type Option struct {
-	vmap			map[string]int
+	vmap			map[string][]int		// indexes of each key in vdata
 	vdata			[]vst
-	optionList		[]opt					// contains all options defined in supplied struct
+	optionList		[]*opt					// contains all options defined in supplied struct
 	args			[]string				// contains all non-option arguments
 	help			[]hp					// contains all help items
 	keys			map[string]bool
@@ -87,55 +125,225 @@ type Option struct {
 	opt_count		int						// A running count of called options and flags
 	argLimit		int						// the maximum number of arguments that may be read from the command line
 	cmd				string
+	argv			[]string				// the argument vector being parsed (argv[0] is the command)
+	name			string					// subcommand name
+	text			string					// subcommand help text
+	commands		[]*Command				// subcommand definitions
+	subs			[]*Option				// one option object for each subcommand
+	sub				*Option					// the subcommand invoked on the command line
+	cmdIndex		int						// index of the subcommand name in argv
+	settings		[]Setting				// settings passed to New, inherited by subcommands
+	envPrefix		string					// prefix for derived environment variable names
+	configPath		string					// default config file path
+	conf			map[string][]string		// values read from the config file
+	confPrefix		string					// prefix of config keys belonging to this subcommand
+	missing			[]*opt					// required options that were not supplied
+	vars			[]interface{}			// the option struct and argument slice pointers
+	validate		bool					// return definition errors instead of panicking
+	defErrs			[]string				// problems found in the definition
+	completers		map[string]func(string) []string	// value completion functions by key
+	helpWidth		int						// width of the help text, or 0 for the default
+	helpIndent		int						// indent of the help text, or 0 for the default
+	helpColumn		int						// column of option help text, or 0 to fit the keys
+	digitKeys		bool					// allow digit unix keys (-1 to -9)
 }
 
+// A Setting alters the behavior of the parser.  Settings may be passed to New
+// in any position along with the option struct and argument slice.
+type Setting func(*Option)
+
 var rx struct {
 	gnuKeywordAssign,
 	gnuKeyword,
 	flag,
+	conventionalTag,
 	nonWord 	*regexp.Regexp
 }
 
 func init () {
 	rx.gnuKeywordAssign	= regexp.MustCompile(`^--(\w[\w-]*)=(.*)$`)
 	rx.gnuKeyword	= regexp.MustCompile(`^--(\w[\w-]*)$`)
-	rx.flag			= regexp.MustCompile(`^-([a-zA-Z]+)$`)
+	rx.flag			= regexp.MustCompile(`^-([a-zA-Z].*)$`)
+	rx.conventionalTag	= regexp.MustCompile(`^\s*(\w+:"(\\.|[^"\\])*"\s*)+$`)
 	rx.nonWord		= regexp.MustCompile(`([^\w]+)`)
 }
 
-// Create a new option object struct.
+// Create a new option object struct from the command line arguments in
+// os.Args.
 //
 func New( v2 ...interface{} ) (*Option,error) {
-	if len(v2) == 0 || len(v2) > 2 {
-		panic("expected one or two arguments")
+	return NewFromArgs(os.Args, v2...)
+}
+
+// Create a new option object struct from the supplied argument vector instead
+// of os.Args.  As with os.Args, the first element is the command name and the
+// remaining elements are the options and arguments to be parsed.
+//
+func NewFromArgs( args []string, v2 ...interface{} ) (*Option,error) {
+	o := newOption(args)
+	v2 = o.configure(v2)
+	if (len(v2) == 0 && len(o.commands) == 0) || len(v2) > 2 {
+		o.defError("expected one or two arguments")
+		v2 = nil
+	}
+	return o, o.run(v2)
+}
+
+// ValidateDefinition causes New to return a DefinitionError listing every
+// problem found in the option struct, argument slice and subcommands, rather
+// than panicking at the first one.  This is useful when option structs come
+// from third-party code.
+func ValidateDefinition() Setting {
+	return func(o *Option) {
+		o.validate = true
 	}
+}
+
+// DigitKeys allows unix keys that are digits, such as -1 to -9 for a
+// compression level.  An argument that begins with a dash and a defined digit
+// key is then read as keys rather than a negative number, so a negative value
+// of such an option must be attached (-n-1) or assigned (--num=-1).  Without
+// this setting, a digit unix key is a definition error.
+func DigitKeys() Setting {
+	return func(o *Option) {
+		o.digitKeys = true
+	}
+}
+
+// report a problem with the option definition.  Panics unless the
+// ValidateDefinition setting was given.
+func (o *Option) defError(msg string) {
+	if !o.validate {
+		panic(msg)
+	}
+	o.defErrs = append(o.defErrs, msg)
+}
+
+// return a DefinitionError if any problems were found in the definition
+func (o *Option) definitionError() error {
+	if len(o.defErrs) == 0 {
+		return nil
+	}
+	return &DefinitionError{Problems: o.defErrs}
+}
+
+func newOption(args []string) *Option {
 	o := &Option{}
-	o.cmd = getCmd()
+	o.argv = args
+	o.cmd = getCmd(args)
 	o.dochead = make(map[string][]string)
-	o.vmap = make(map[string]int)
+	o.vmap = make(map[string][]int)
 	o.keys = make(map[string]bool)
-	o.calcArgLimit(v2)
-	o.parse()
-	if err := o.varAssign(v2); err != nil {
-		return o, err
+	o.completers = make(map[string]func(string) []string)
+	return o
+}
+
+// separate subcommand definitions and settings from the option struct and
+// argument slice, and apply the settings
+func (o *Option) configure(v2 []interface{}) []interface{} {
+	var vars []interface{}
+	for _,vi := range v2 {
+		switch x := vi.(type) {
+		case *Command:
+			o.commands = append(o.commands, x)
+		case Setting:
+			o.settings = append(o.settings, x)
+			x(o)
+		default:
+			vars = append(vars, vi)
+		}
+	}
+	return vars
+}
+
+// define the options and subcommands, then parse the argument vector and
+// assign the results
+func (o *Option) run(v2 []interface{}) error {
+	o.defineAll(v2)
+	if err := o.definitionError(); err != nil {
+		return err
+	}
+	if len(o.completers) > 0 && len(o.argv) > 1 && o.argv[1] == complete_cmd {
+		// exits, unless a test has replaced exit
+		o.completeRequest()
+		return nil
+	}
+	return o.assign()
+}
+
+// check the option struct and argument slice, generate the option list and
+// define the subcommands
+func (o *Option) defineAll(v2 []interface{}) {
+	o.vars = o.checkVars(v2)
+	o.calcArgLimit(o.vars)
+	o.define(o.vars)
+	o.defineCommands()
+}
+
+// parse the argument vector and assign the results
+func (o *Option) assign() error {
+	if err := o.parse(); err != nil {
+		return err
+	}
+	if err := o.loadConfig(); err != nil {
+		return err
+	}
+	if err := o.varAssign(o.vars); err != nil {
+		return err
 	}
 	if err := o.checkUndefinedOptions(); err != nil {
-		return o, err
+		return err
+	}
+	if err := o.checkCommand(); err != nil {
+		return err
 	}
-	return o, nil
+	if err := o.checkRequiredOptions(); err != nil {
+		return err
+	}
+	return o.runCommands()
 }
 
-// calculate a limit for the number of arguments to be read from os.Args
-func (o *Option) calcArgLimit (v2 []interface{}) {
-	for _,vi := range v2 {
+// check that the supplied vars are an option struct pointer, an argument slice
+// or array pointer, or a struct pointer followed by a slice or array pointer.
+// Returns the vars that are valid.
+func (o *Option) checkVars(v2 []interface{}) []interface{} {
+	var vars []interface{}
+	for i,vi := range v2 {
 		v := reflect.ValueOf(vi)
 		if v.Kind() != reflect.Ptr {
-			panic("expected struct or slice pointer")
+			o.defError("expected struct or slice pointer")
+			continue
 		}
-		v = v.Elem()
-		switch v.Kind() {
+		switch v.Elem().Kind() {
 		case reflect.Struct:
-			o.argLimit += v.NumField()
+			if i == 1 {
+				o.defError("second argument should not be a struct pointer")
+				continue
+			}
+		case reflect.Slice:
+			if i == 0 && len(v2) == 2 {
+				o.defError("first argument cannot be a slice")
+				continue
+			}
+		case reflect.Array:
+			if i == 0 && len(v2) == 2 {
+				o.defError("first argument cannot be an array")
+				continue
+			}
+		default:
+			o.defError("expected struct or slice pointer")
+			continue
+		}
+		vars = append(vars, vi)
+	}
+	return vars
+}
+
+// calculate a limit for the number of arguments to be read from os.Args
+func (o *Option) calcArgLimit (v2 []interface{}) {
+	for _,vi := range v2 {
+		v := reflect.ValueOf(vi).Elem()
+		switch v.Kind() {
 		case reflect.Slice:
 			if v.Cap() == 0 {
 				o.argLimit = slice_limit
@@ -151,32 +359,41 @@ func (o *Option) calcArgLimit (v2 []interface{}) {
 	}
 }
 
-// Returns the path of this executable (os.Args[0])
+// Returns the path of this executable (the first element of the argument
+// vector, normally os.Args[0])
 func (o *Option) Cmd() string {
-	return os.Args[0]
+	if len(o.argv) == 0 {
+		return ""
+	}
+	return o.argv[0]
+}
+
+// Generate the option list from the option struct, if one was supplied, and
+// note the argument slice
+func (o *Option) define( v2 []interface{} ) {
+	for _,vi := range v2 {
+		v := reflect.ValueOf(vi).Elem()
+		switch v.Kind() {
+		case reflect.Struct:
+			o.genoptionList(v)
+		case reflect.Slice, reflect.Array:
+			o.hasArgSlice = true
+			o.argSliceRef = v
+		}
+	}
 }
 
 // Assign command line options and arguments to option struct and arg slice
-// By the way, getOptions must be called before getArgs you will get the wrong args.
 func (o *Option) varAssign( v2 []interface{} ) error {
-	for i,vi := range v2 {
+	for _,vi := range v2 {
 		v := reflect.ValueOf(vi).Elem()
 		switch v.Kind() {
 		case reflect.Struct:
-			if i == 1 {
-				panic("second argument should not be a struct pointer")
-			}
-			o.genoptionList(v)
 			err := o.getOptions(v)
 			if err != nil {
 				return err
 			}
 		case reflect.Slice:
-			if i == 0 && len(v2) == 2 {
-				panic("first argument cannot be a slice")
-			}
-			o.hasArgSlice = true
-			o.argSliceRef = v
 			args, err := o.getArgs()
 			if err != nil {
 				return err
@@ -186,11 +403,6 @@ func (o *Option) varAssign( v2 []interface{} ) error {
 				return err
 			}
 		case reflect.Array:
-			if i == 0 && len(v2) == 2 {
-				panic("first argument cannot be an array")
-			}
-			o.hasArgSlice = true
-			o.argSliceRef = v
 			args, err := o.getArgs()
 			if err != nil {
 				return err
@@ -199,8 +411,6 @@ func (o *Option) varAssign( v2 []interface{} ) error {
 			if err != nil {
 				return err
 			}
-		default:
-			panic("expected struct or slice pointer")
 		}
 	}
 	return nil
@@ -211,15 +421,14 @@ func (o *Option) getArgs() ([]string, error) {
 	count := 0
 	// scan the vdata array looking for unassigned arguments
 	for _,v := range o.vdata {
-		if v.typ == typ_arg || v.typ == typ_flag {
-			if count++; count > o.argLimit {
-				return args, fmt.Errorf("number of arguments supplied exceeds limit (%v)", o.argSliceCap)
-			}
-			if v.val != "" {
-				args = append(args, v.val)
-			}
+		if v.typ == typ_arg {
+			count++
+			args = append(args, v.val)
 		}
 	}
+	if count > o.argLimit {
+		return args, &TooManyArgsError{Limit: o.argSliceCap, Got: count}
+	}
 	return args, nil
 }
 
@@ -237,7 +446,7 @@ func (o *Option) setArray(args []string) error {
 //			break
 //		}
 		if err := setScalar(v.Index(i), args[i]); err != nil {
-			return err
+			return invalidValue(v.Index(i), args[i], err)
 		}
 	}
 	return nil
@@ -246,7 +455,7 @@ func (o *Option) setArray(args []string) error {
 
 // HasArgs will return true if any flag, option or argument was supplied.
 func (o *Option) HasArgs () bool {
-	return len(os.Args) > 1
+	return len(o.argv) > 1
 }
 
 func (o *Option) checkUndefinedOptions () error {
@@ -259,139 +468,509 @@ func (o *Option) checkUndefinedOptions () error {
 	if len(xtra) == 0 {
 		return nil
 	}
-	msg := "Invalid command line option"
-	if len(xtra) > 1 {
-		msg += "s"
+	return &UnknownOptionError{Keys: xtra}
+}
+
+// return an error listing every required option that was not supplied
+func (o *Option) checkRequiredOptions () error {
+	if len(o.missing) == 0 {
+		return nil
+	}
+	var keys []string
+	for _,x := range o.missing {
+		keys = append(keys, x.keyString())
 	}
-	msg += ": (" + strings.Join(xtra, ", ") + ")"
-	return errors.New(msg)
+	return &MissingOptionError{Keys: keys}
 }
 
-func (o *Option) parse() {
-	_lastkey := ""
-	for i,arg := range os.Args {
+// split the argument vector into keys, values and arguments.  Returns a
+// MissingValueError if an option that takes a value is followed by another key
+// or by nothing.
+func (o *Option) parse() error {
+	// the key of an option that takes its value from the next argument
+	valueKey := ""
+	for i,arg := range o.argv {
 		if i == 0 {
 			continue
 		}
-		if len(arg) > 0 && '-' == arg[0] {
-			m := rx.gnuKeywordAssign.FindStringSubmatch(arg)
-			if m != nil {
-				// A GNU-style keyword with assignment (keyword=value)
-				key := m[1]
-				val := m[2]
-				_lastkey = key
-				o.vdata = append(o.vdata, vst{key,strings.Trim(val, qt),typ_uoption})
-				o.vmap[key] = len(o.vdata) -1
-				continue
-			}
-			m = rx.gnuKeyword.FindStringSubmatch(arg)
-			if m != nil {
-				// A GNU-style keyword alone
-				key := m[1]
-				_lastkey = key
-				o.vdata = append(o.vdata, vst{key,"",0})
-				o.vmap[key] = len(o.vdata) -1
-				continue
-			}
-			m = rx.flag.FindStringSubmatch(arg)
-			if m != nil {
-				// A UNIX-style flag
-				for _,c := range m[1] {
-					key := string(c)
-					_lastkey = key
-					o.vdata = append(o.vdata, vst{key,"",0})
-					o.vmap[key] = len(o.vdata) -1
-				}
-				continue
+		if valueKey != "" && (arg == "--" || o.isKey(arg)) {
+			return &MissingValueError{Key: valueKey}
+		}
+		if arg == "--" {
+			// end of options: the remaining arguments are taken as they are
+			for _,a := range o.argv[i+1:] {
+				o.vdata = append(o.vdata, vst{"",a,typ_arg})
 			}
+			break
 		}
-		if _lastkey != "" {
+		if valueKey != "" {
 			// Assign the argument to the value of the last key
 			ndx := len(o.vdata) -1	// index of the last data item
 			o.vdata[ndx].val = strings.Trim(arg, qt)
-			_lastkey = ""
+			valueKey = ""
 			continue
-		} else {
-			// No key. Just an argument by itself.
-			// Append it to vdata with a blank key
-			o.vdata = append(o.vdata, vst{"",strings.Trim(arg, qt),typ_arg})
+		}
+		if o.isCommand(arg) {
+			// everything after the subcommand name belongs to the subcommand
+			o.cmdIndex = i
+			break
+		}
+		if m := rx.gnuKeywordAssign.FindStringSubmatch(arg); m != nil {
+			// A GNU-style keyword with assignment (keyword=value)
+			key := m[1]
+			val := m[2]
+			o.vdata = append(o.vdata, vst{key,strings.Trim(val, qt),typ_uoption})
+			o.vmap[key] = append(o.vmap[key], len(o.vdata) -1)
+			continue
+		}
+		if m := rx.gnuKeyword.FindStringSubmatch(arg); m != nil {
+			// A GNU-style keyword alone
+			key := m[1]
+			if x := o.negated(key); x != nil {
+				// --no-key sets a bool option to false
+				o.vdata = append(o.vdata, vst{x.gnu_key,"false",typ_uoption})
+				o.vmap[x.gnu_key] = append(o.vmap[x.gnu_key], len(o.vdata) -1)
+				continue
+			}
+			o.vdata = append(o.vdata, vst{key,"",0})
+			o.vmap[key] = append(o.vmap[key], len(o.vdata) -1)
+			if o.takesValue(key) {
+				valueKey = key
+			}
+			continue
+		}
+		if o.isFlags(arg) {
+			// A UNIX-style flag, or several, perhaps with an attached value
+			keys, val, attached := o.splitFlags(arg[1:])
+			for _,key := range keys {
+				o.vdata = append(o.vdata, vst{key,"",0})
+				o.vmap[key] = append(o.vmap[key], len(o.vdata) -1)
+			}
+			key := keys[len(keys)-1]
+			if attached {
+				o.vdata[len(o.vdata)-1].val = strings.Trim(val, qt)
+			} else if o.takesValue(key) {
+				valueKey = key
+			}
+			continue
+		}
+		// No key. Just an argument by itself.
+		// Append it to vdata with a blank key
+		o.vdata = append(o.vdata, vst{"",strings.Trim(arg, qt),typ_arg})
+	}
+	if valueKey != "" {
+		return &MissingValueError{Key: valueKey}
+	}
+	return nil
+}
+
+// split a cluster of unix keys (-abc) into its keys.  The first key that takes
+// a value ends the cluster, and the rest of the argument is its value
+// (-p8080, -vofile).
+func (o *Option) splitFlags(s string) (keys []string, val string, attached bool) {
+	for i,c := range s {
+		key := string(c)
+		keys = append(keys, key)
+		if o.takesValue(key) {
+			if rest := s[i+len(key):]; rest != "" {
+				return keys, rest, true
+			}
+			break
 		}
 	}
+	return keys, "", false
+}
+
+// return true if the argument is an option key rather than a value.  Negative
+// numbers are values.
+func (o *Option) isKey(arg string) bool {
+	return arg == "--" || rx.gnuKeywordAssign.MatchString(arg) ||
+		rx.gnuKeyword.MatchString(arg) || o.isFlags(arg)
+}
+
+// return true if the argument is one or more unix keys.  These begin with a
+// letter, or with a digit key in DigitKeys mode.  Otherwise an argument such
+// as -5 or -1e3 is a number.
+func (o *Option) isFlags(arg string) bool {
+	if rx.flag.MatchString(arg) {
+		return true
+	}
+	return o.digitKeys && len(arg) > 1 && arg[0] == '-' && isDigit(arg[1]) &&
+		o.lookup(arg[1:2]) != nil
+}
+
+// return true if the option with the supplied key takes its value from the
+// next argument.  Bool options never do.
+func (o *Option) takesValue(key string) bool {
+	if x := o.lookup(key); x != nil {
+		return !x.isFlag()
+	}
+	// the automatic --config option
+	return key == config_key
+}
+
+// return the bool option negated by a keyword such as no-color, or nil.  An
+// option defined with the keyword itself takes precedence.
+func (o *Option) negated(key string) *opt {
+	if !strings.HasPrefix(key, "no-") || o.lookup(key) != nil {
+		return nil
+	}
+	if x := o.lookup(key[3:]); x != nil && x.gnu_key == key[3:] && x.negatable() {
+		return x
+	}
+	return nil
+}
+
+// return the option with the supplied unix or gnu key, or nil
+func (o *Option) lookup(key string) *opt {
+	for _,x := range o.optionList {
+		if x.u_key == key || x.gnu_key == key {
+			return x
+		}
+	}
+	return nil
 }
 
 // generate option list. check data types while we are here.
 func (o *Option) genoptionList(v reflect.Value) {
+	o.genGroup(v, "")
+//	if o.opt_count == 0 {
+//		 panic("no public options defined in struct")
+//	}
+}
+
+// generate the options of a struct.  Nested structs are option groups that
+// follow the other options of the struct.  Gnu keys within a named group are
+// prefixed with the group keyword.
+func (o *Option) genGroup(v reflect.Value, prefix string) {
+	var groups []int
 	for n, nf := 0, v.NumField(); n < nf; n++ {
 		fld := v.Field(n)
 		//Note: better way?
 		name := v.Type().Field(n).Name
-		tag := string(v.Type().Field(n).Tag)
-		typ := fld.Type().String()
+		spec, attr := splitTag(string(v.Type().Field(n).Tag))
+		typ := typeName(fld.Type())
 		if !isPublic(name) {
-			panic(fmt.Sprintf("private field not allowed (%s)", name))
+			o.defError(fmt.Sprintf("private field not allowed (%s)", name))
+			continue
 		}
-		if !isScalar(fld) {
-			panic(fmt.Sprintf("type %v not allowed (%s)", fld.Kind(), name))
+		if fld.Kind() == reflect.Struct && !isScalar(fld) {
+			groups = append(groups, n)
+			continue
+		}
+		if isCustom(fld.Type()) {
+			// decoded by its own Value or TextUnmarshaler interface
+		} else if fld.Kind() == reflect.Slice {
+			// a repeatable option of scalar elements
+			if !isScalar(reflect.New(fld.Type().Elem()).Elem()) {
+				o.defError(fmt.Sprintf("type %v not allowed (%s)", fld.Type(), name))
+				continue
+			}
+			typ = typeName(fld.Type().Elem())
+		} else if fld.Kind() == reflect.Map {
+			// a repeatable option of key=value pairs
+			if fld.Type().Key().Kind() != reflect.String ||
+					!isScalar(reflect.New(fld.Type().Elem()).Elem()) {
+				o.defError(fmt.Sprintf("type %v not allowed (%s)", fld.Type(), name))
+				continue
+			}
+			typ = "key=" + typeName(fld.Type().Elem())
+		} else if !isScalar(fld) {
+			o.defError(fmt.Sprintf("type %v not allowed (%s)", fld.Kind(), name))
+			continue
+		}
+		u_key, gnu_key, placeholder, text := o.createKeyNames(name, typ, spec, prefix)
+		if d,ok := attr.Lookup("default"); ok {
+			// decode the default into a scratch value to check it
+			scratch := opt{fld: reflect.New(fld.Type()).Elem(), attr: attr}
+			if err := scratch.set([]string{d}); err != nil {
+				o.defError(fmt.Sprintf("invalid default value (%s): %s", name, err))
+			}
 		}
-		u_key, gnu_key, placeholder, text := o.createKeyNames(name, typ, tag)
 		o.opt_count++
-		// items in optionList are indexed with fields in supplied option struct
-		o.optionList = append(o.optionList, opt{fld, name, typ, u_key, gnu_key, text, placeholder})
-		o.help = append(o.help, hp{&o.optionList[n], "", []string{}, typ_option})
+		env := o.envName(gnu_key, attr)
+		x := &opt{fld, name, typ, u_key, gnu_key, text, placeholder, attr, env}
+		o.optionList = append(o.optionList, x)
+		o.help = append(o.help, hp{x, "", []string{}, typ_option})
 	}
-//	if o.opt_count == 0 {
-//		 panic("no public options defined in struct")
-//	}
+	for _,n := range groups {
+		o.genSubgroup(v.Field(n), v.Type().Field(n), prefix)
+	}
+}
+
+// generate an option group from a nested struct.  An embedded struct is
+// flattened into the enclosing struct, while a named struct prefixes its gnu
+// keys with the field keyword (--db-host).  The tag may give the group keyword
+// and heading (db:DATABASE), or the heading alone.  Otherwise the heading is
+// the field name.
+func (o *Option) genSubgroup(fld reflect.Value, field reflect.StructField, prefix string) {
+	spec, _ := splitTag(string(field.Tag))
+	heading := toUpper(strings.Replace(camelToSnake(field.Name), "_", " ", -1))
+	keyword := ""
+	if !field.Anonymous {
+		keyword = strings.Replace(camelToSnake(field.Name), "_", "-", -1)
+	}
+	if a := strings.SplitN(spec, delimiter, 2); len(a) == 2 {
+		if !field.Anonymous {
+			keyword = a[0]
+		}
+		heading = a[1]
+	} else if spec != "" {
+		heading = spec
+	}
+	switch {
+	case keyword == "":
+		keyword = prefix
+	case prefix != "":
+		keyword = prefix + "-" + keyword
+	}
+	o.help = append(o.help, hp{nil, heading, []string{}, typ_group})
+	o.genGroup(fld, keyword)
 }
 
 // assign all of the options to our struct
 func (o *Option) getOptions(v reflect.Value) error {
 	for _,x := range o.optionList {
-		fld := x.fld
-		u_key := x.u_key
-		gnu_key := x.gnu_key
-		ndx,ok := o.vmap[u_key]
-		key := u_key
-		if !ok {
-			if ndx,ok = o.vmap[gnu_key]; !ok {
-				continue
+		ndxs := o.occurrences(x)
+		if len(ndxs) == 0 {
+			found, err := o.setFallback(x)
+			if err != nil {
+				return err
 			}
-			key = gnu_key
-		}
-		var val string
-		switch fld.Kind() {
-		case reflect.Bool:
-			val = "1"
-			if o.vdata[ndx].typ == typ_uoption {
-				if val = o.vdata[ndx].val; val == "" {
-					val = "0"
+			if !found && x.required() {
+				o.missing = append(o.missing, x)
+			}
+			continue
+		}
+		var key string
+		var vals []string
+		for _,ndx := range ndxs {
+			key = o.vdata[ndx].key
+			switch {
+			case x.isFlag():
+				val := "1"
+				if o.vdata[ndx].typ == typ_uoption {
+					if val = o.vdata[ndx].val; val == "" {
+						val = "0"
+					}
 				}
+				o.vdata[ndx].typ = typ_flag
+				vals = append(vals, val)
+			default:
+				vals = append(vals, o.vdata[ndx].val)
+				o.vdata[ndx].typ = typ_option
 			}
-			o.vdata[ndx].typ = typ_flag
-		default:
-			val = o.vdata[ndx].val
-			o.vdata[ndx].typ = typ_option
 		}
-		if err := setScalar(fld, val); err != nil {
-			return errors.New(err.Error() + ` "`+key+`"`)
+		if err := x.set(vals); err != nil {
+			return withKey(err, key)
+		}
+	}
+	return nil
+}
+
+// return the vdata indexes of every occurrence of an option, in command line
+// order
+func (o *Option) occurrences(x *opt) []int {
+	var ndxs []int
+	for _,key := range []string{x.u_key, x.gnu_key} {
+		if key != "" {
+			ndxs = append(ndxs, o.vmap[key]...)
+		}
+	}
+	sort.Ints(ndxs)
+	return ndxs
+}
+
+// assign values to an option field.  A repeatable option accumulates all of
+// the values, splitting each one with the tag separator if there is one.  Any
+// other option takes the last value.
+func (x opt) set(vals []string) error {
+	if !x.repeatable() {
+		return x.decode(x.fld, vals[len(vals)-1])
+	}
+	if sep := x.attr.Get("sep"); sep != "" {
+		var items []string
+		for _,val := range vals {
+			items = append(items, strings.Split(val, sep)...)
+		}
+		vals = items
+	}
+	if x.fld.Kind() == reflect.Map {
+		return x.setMap(vals)
+	}
+	s := reflect.MakeSlice(x.fld.Type(), len(vals), len(vals))
+	for i,val := range vals {
+		if err := x.decode(s.Index(i), val); err != nil {
+			return err
 		}
 	}
+	x.fld.Set(s)
 	return nil
 }
 
+// decode a single value of the option.  If the tag has an enum key, the value
+// must be one of its comma separated choices.
+func (x opt) decode(v1 reflect.Value, val string) error {
+	if choices := x.choices(); choices != nil && x.attr.Get("enum") != "" {
+		found := false
+		for _,c := range choices {
+			found = found || c == val
+		}
+		if !found {
+			err := errors.New("expected one of (" + strings.Join(choices, ", ") + ")")
+			return invalidValue(v1, val, err)
+		}
+	}
+	if err := setScalar(v1, val); err != nil {
+		return invalidValue(v1, val, err)
+	}
+	return nil
+}
+
+// return the values an option may take: the enum choices of the tag, or true
+// and false for a bool.  Returns nil if the values are not known.
+func (x opt) choices() []string {
+	if enum := x.attr.Get("enum"); enum != "" {
+		return strings.Split(enum, ",")
+	}
+	if x.fld.Kind() == reflect.Bool {
+		return []string{"true", "false"}
+	}
+	return nil
+}
+
+// assign key=value pairs to a map option.  A duplicate key is an error if the
+// tag has unique:"true", otherwise the last value wins.
+func (x opt) setMap(vals []string) error {
+	unique := x.attr.Get("unique") == "true"
+	m := reflect.MakeMap(x.fld.Type())
+	for _,val := range vals {
+		kv := strings.SplitN(val, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return invalidValue(x.fld, val, errors.New("expected key=value"))
+		}
+		key := reflect.ValueOf(kv[0]).Convert(x.fld.Type().Key())
+		if unique && m.MapIndex(key).IsValid() {
+			return invalidValue(x.fld, val, errors.New("duplicate key ("+kv[0]+")"))
+		}
+		elem := reflect.New(x.fld.Type().Elem()).Elem()
+		if err := x.decode(elem, kv[1]); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, elem)
+	}
+	x.fld.Set(m)
+	return nil
+}
+
+// return true if the tag marks the option as required
+func (x opt) required() bool {
+	return x.attr.Get("required") == "true"
+}
+
+// return the option keys joined for display (-p/--port)
+func (x opt) keyString() string {
+	var keys []string
+	if x.u_key != "" {
+		keys = append(keys, "-" + x.u_key)
+	}
+	if x.gnu_key != "" {
+		keys = append(keys, "--" + x.gnu_key)
+	}
+	return strings.Join(keys, "/")
+}
+
+// return true if the option may be given more than once
+func (x opt) repeatable() bool {
+	if isCustom(x.fld.Type()) {
+		return false
+	}
+	return x.fld.Kind() == reflect.Slice || x.fld.Kind() == reflect.Map
+}
+
+// return true if a bool option also accepts --no-key.  This may be disabled
+// with negate:"false" in the tag.
+func (x opt) negatable() bool {
+	return x.isFlag() && x.gnu_key != "" && x.attr.Get("negate") != "false"
+}
+
+// return true if the option is a flag that takes no value
+func (x opt) isFlag() bool {
+	if isCustom(x.fld.Type()) {
+		return false
+	}
+	switch x.fld.Kind() {
+	case reflect.Bool:
+		return true
+	case reflect.Slice:
+		return x.fld.Type().Elem().Kind() == reflect.Bool
+	}
+	return false
+}
+
+// assign a value to an option not given on the command line.  The environment
+// variable takes precedence over the config file, and the config file takes
+// precedence over the tag default.
+func (o *Option) setFallback(x *opt) (bool, error) {
+	if val,ok := x.lookupEnv(); ok {
+		if err := x.set([]string{val}); err != nil {
+			return true, withKey(err, x.env)
+		}
+		return true, nil
+	}
+	if vals,ok := o.confValues(x); ok {
+		if err := x.set(vals); err != nil {
+			return true, withKey(err, x.gnu_key)
+		}
+		return true, nil
+	}
+	d,ok := x.attr.Lookup("default")
+	if !ok {
+		return false, nil
+	}
+	return true, withKey(x.set([]string{d}), x.gnu_key)
+}
+
+// the keys of a conventional struct tag that are read by this package
+var tag_keys = []string{"opt", "default", "env", "enum", "negate", "required", "sep", "unique"}
+
+// A struct tag is either the colon separated form (key:keyword:placeholder:help)
+// or a conventional tag of key:"value" pairs.  In the conventional form, the
+// colon separated spec is given by the "opt" key.
+//
+//   Port int `opt:"p:port:number:Port to listen on" default:"8080"`
+//
+// A tag is only read in the conventional form if it has one of the tag_keys,
+// so that a colon separated tag with quoted help text (q:"quoted") keeps its
+// meaning.
+func splitTag(tag string) (spec string, attr reflect.StructTag) {
+	if !rx.conventionalTag.MatchString(tag) {
+		return tag, ""
+	}
+	attr = reflect.StructTag(tag)
+	for _,key := range tag_keys {
+		if _,ok := attr.Lookup(key); ok {
+			return attr.Get("opt"), attr
+		}
+	}
+	return tag, ""
+}
+
 // if struct tag is defined, parse it for u_key, gnu_key, help text and value placeholder
 // if not, create them.
-func (o *Option) createKeyNames(name, typ, tag string) (u_key, gnu_key, placeholder, help string) {
+// Within a named option group, gnu keys are prefixed with the group keyword and
+// no unix key is created automatically.
+func (o *Option) createKeyNames(name, typ, tag, prefix string) (u_key, gnu_key, placeholder, help string) {
 	placeholder = typ
 	if tag == "" {
-		u_key, gnu_key = o.autoKeys(name)
+		u_key, gnu_key = o.autoKeys(name, prefix)
 		return
 	}
 	a := strings.SplitN(tag,delimiter,4) // no more than 4 items in the tag
 	switch len(a) {
 		case 0,1:
 			help = tag
-			u_key, gnu_key = o.autoKeys(name)
+			u_key, gnu_key = o.autoKeys(name, prefix)
 			return
 		case 2:
 			help = a[1]
@@ -403,7 +982,8 @@ func (o *Option) createKeyNames(name, typ, tag string) (u_key, gnu_key, placehol
 		case 3:
 			help = a[2]
 			if len(a[0]) > 1 {
-				panic("unix tyle flag should be a single character")
+				o.defError("unix style flag should be a single character ("+name+")")
+				a[0] = ""
 			}
 			u_key = a[0]
 			gnu_key = a[1]
@@ -411,15 +991,23 @@ func (o *Option) createKeyNames(name, typ, tag string) (u_key, gnu_key, placehol
 			help = a[3]
 			placeholder = a[2]
 			if len(a[0]) > 1 {
-				panic("unix tyle flag should be a single character")
+				o.defError("unix style flag should be a single character ("+name+")")
+				a[0] = ""
 			}
 			u_key = a[0]
 			gnu_key = a[1]
 	}
+	if prefix != "" && gnu_key != "" {
+		gnu_key = prefix + "-" + gnu_key
+	}
+	if len(u_key) == 1 && isDigit(u_key[0]) && !o.digitKeys {
+		o.defError("digit keys require the DigitKeys setting ("+name+")")
+		u_key = ""
+	}
 	// check key
 	// panic if u_key or gnu_key is aleady used
 	if err := o.keyCheck(u_key, gnu_key); err != nil {
-		panic(err.Error())
+		o.defError(err.Error())
 	}
 	return
 }
@@ -444,9 +1032,17 @@ func (o *Option) keyCheck(u_key, gnu_key string) error {
 }
 
 // Create key names from name
-func (o *Option) autoKeys(name string) (u_key, gnu_key string) {
-	u_key = toLower(name[0:1])
+func (o *Option) autoKeys(name, prefix string) (u_key, gnu_key string) {
 	gnu_key = strings.Replace(toLower(camelToSnake(name)), "_", "-", -1)
+	if prefix != "" {
+		gnu_key = prefix + "-" + gnu_key
+		if _,ok := o.keys[gnu_key]; ok {
+			return "", ""
+		}
+		o.keys[gnu_key] = true
+		return "", gnu_key
+	}
+	u_key = toLower(name[0:1])
 	// check if u_key already exists
 	// if u_key has already been used, try uppercase.
 	// if upper case u_key has already been used, return blank.
@@ -505,6 +1101,10 @@ func camelToSnake(s string) string {
 	return bs
 }
 
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
 func isLower(c byte) bool {
 	return c >= 'a' && c <= 'z'
 }
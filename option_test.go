@@ -6,6 +6,7 @@ package option
 
 import (
 	"time"
+	"errors"
 	"testing"
 )
 
@@ -134,9 +135,9 @@ func TestNew( t *testing.T ) {
 	})
 
     myTest("Given option struct with disallowed data types", t, func() {
-		// given slice
+		// given slice of slices
 		ShouldPanic(func(){
-			var my struct{ Words []string}
+			var my struct{ Words [][]string}
 			New(&my)
 		})
 		// given private field
@@ -226,6 +227,42 @@ func TestNew( t *testing.T ) {
 	resetArgs()
 }
 
+func TestNewFromArgs( t *testing.T ) {
+
+    myTest("Given an explicit argument vector", t, func() {
+		var my struct{ Answer int; Towel bool }
+		var args []string
+		op,err := NewFromArgs([]string{"/mypath/mycommand", "-a", "42", "-t", "Vogon"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( op.Cmd(), "/mypath/mycommand" )
+		ShouldEqual( op.cmd, "mycommand" )
+		ShouldBeTrue( op.HasArgs() )
+		ShouldEqual( my.Answer, 42 )
+		ShouldBeTrue( my.Towel )
+		ShouldEqual( len(args), 1 )
+		ShouldEqual( args[0], "Vogon" )
+	})
+
+    myTest("Given an argument vector, os.Args should not be read", t, func() {
+		setArgs( arg0, "-a", "7" )
+		var my struct{ Answer int }
+		op,err := NewFromArgs([]string{arg0}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( !op.HasArgs() )
+		ShouldEqual( my.Answer, 0 )
+		resetArgs()
+	})
+
+    myTest("Given an empty argument vector", t, func() {
+		var my struct{ Answer int }
+		op,err := NewFromArgs(nil, &my)
+		ShouldNotError( err )
+		ShouldEqual( op.Cmd(), "" )
+		ShouldBeTrue( !op.HasArgs() )
+	})
+
+}
+
 func Test_misc( t *testing.T ) {
 
     myTest("Given a blank assignment to a bool", t, func() {
@@ -308,14 +345,11 @@ func Test_options( t *testing.T ) {
 
 	//
 	op_tests = st{
-		{ arg0, "-f" },
 		{ arg0, "-f", "" },
 		{ arg0, "-f", `""` },
-		{ arg0, "-f", "-o" },
-		{ arg0, "--file" },
 		{ arg0, "--file", "" },
 		{ arg0, "--file", `""` },
-		{ arg0, "--file", "-o" },
+		{ arg0, "--file=" },
 	}
 
     myTest("Given an enpty option", t, func() {
@@ -331,6 +365,28 @@ func Test_options( t *testing.T ) {
 		}
 	})
 
+	//
+	op_tests = st{
+		{ arg0, "-f" },
+		{ arg0, "-f", "-o" },
+		{ arg0, "--file" },
+		{ arg0, "--file", "-o" },
+	}
+
+    myTest("Given an option without a value", t, func() {
+		var my struct {
+			File		string
+			Other		bool
+		}
+		for ndx := 0; ndx < len(op_tests); ndx++ {
+			setArgs(op_tests[ndx]...)
+			_,err := New(&my)
+			var e *MissingValueError
+			ShouldBeTrue( errors.As(err, &e) )
+			resetArgs()
+		}
+	})
+
     myTest("Given flags ganged with one string option", t, func() {
 		type myX struct {
 			Creator, Towel bool
@@ -394,6 +450,161 @@ func Test_options( t *testing.T ) {
 
 }
 
+func Test_defaults( t *testing.T ) {
+
+	type mySt struct{
+		Port	int			`opt:"p:port:number:Port to listen on" default:"8080"`
+		Host	string		`default:"localhost"`
+		Debug	bool		`default:"yes"`
+		Answer	int
+	}
+
+    myTest("Given tag defaults and no command line options", t, func() {
+		my := mySt{Answer: 42}
+		_,err := NewFromArgs([]string{arg0}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Port, 8080 )
+		ShouldEqual( my.Host, "localhost" )
+		ShouldBeTrue( my.Debug )
+		ShouldEqual( my.Answer, 42 )
+	})
+
+    myTest("Given tag defaults overridden on the command line", t, func() {
+		my := mySt{}
+		_,err := NewFromArgs([]string{arg0, "-p", "9090", "--host=vogon", "--debug=no"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Port, 9090 )
+		ShouldEqual( my.Host, "vogon" )
+		ShouldBeTrue( !my.Debug )
+	})
+
+    myTest("Given an invalid tag default", t, func() {
+		var my struct{
+			Port	int		`default:"eighty"`
+		}
+		ShouldPanic(func(){
+			NewFromArgs([]string{arg0}, &my)
+		})
+	})
+
+}
+
+func Test_repeatable( t *testing.T ) {
+
+	type mySt struct{
+		Include	[]string
+		Tag		[]string	`sep:","`
+		Level	[]int		`opt:"L:level:n:" sep:","`
+		Verbose	[]bool
+	}
+
+    myTest("Given repeated options", t, func() {
+		var my mySt
+		argv := []string{arg0, "-i", "a", "--include", "b", "-i", "c", "-vvv", "--verbose"}
+		_,err := NewFromArgs(argv, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Include, []string{"a", "b", "c"} )
+		ShouldEqual( my.Verbose, []bool{true, true, true, true} )
+	})
+
+    myTest("Given separated values", t, func() {
+		var my mySt
+		argv := []string{arg0, "--tag=x,y,z", "-t", "w", "-L", "1,2", "--level=3"}
+		_,err := NewFromArgs(argv, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Tag, []string{"x", "y", "z", "w"} )
+		ShouldEqual( my.Level, []int{1, 2, 3} )
+	})
+
+    myTest("Given an invalid element value", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--level=1,two"}, &my)
+		ShouldError( err, `strconv.ParseInt: parsing "two": invalid syntax "level"` )
+	})
+
+    myTest("Given repeatable options from defaults, environment and config", t, func() {
+		t.Setenv("MYAPP_TAG", "x,y")
+		path := writeConfig(t, "my.json", `{"include": ["a", "b"]}`)
+		var my struct{
+			Include	[]string
+			Tag		[]string	`sep:","`
+			Level	[]int		`sep:"," default:"1,2"`
+		}
+		_,err := NewFromArgs([]string{arg0, "--config=" + path}, &my, EnvPrefix("MYAPP"))
+		ShouldNotError( err )
+		ShouldEqual( my.Include, []string{"a", "b"} )
+		ShouldEqual( my.Tag, []string{"x", "y"} )
+		ShouldEqual( my.Level, []int{1, 2} )
+	})
+
+}
+
+func Test_maps( t *testing.T ) {
+
+	type mySt struct{
+		Label	map[string]string
+		Define	map[string]int		`opt:"D:define:key=value:" unique:"true"`
+		Flag	map[string]bool		`sep:","`
+	}
+
+    myTest("Given key=value options", t, func() {
+		var my mySt
+		argv := []string{arg0, "--label", "env=prod", "-l", "team=core", "-D", "x=1"}
+		_,err := NewFromArgs(argv, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Label, map[string]string{"env": "prod", "team": "core"} )
+		ShouldEqual( my.Define, map[string]int{"x": 1} )
+	})
+
+    myTest("Given separated key=value pairs", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--flag=a=yes,b=no"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Flag, map[string]bool{"a": true, "b": false} )
+	})
+
+    myTest("Given a duplicate key, the last value wins", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-l", "env=dev", "-l", "env=prod"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Label, map[string]string{"env": "prod"} )
+	})
+
+    myTest("Given a duplicate key in a unique map", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-D", "x=1", "--define=x=2"}, &my)
+		ShouldError( err, `duplicate key (x) "define"` )
+	})
+
+    myTest("Given invalid key=value pairs", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-l", "env"}, &my)
+		ShouldError( err, `expected key=value "l"` )
+		_,err = NewFromArgs([]string{arg0, "-D", "x=one"}, &my)
+		ShouldError( err, `strconv.ParseInt: parsing "one": invalid syntax "D"` )
+	})
+
+    myTest("Given map entries in a config file section", t, func() {
+		path := writeConfig(t, "my.conf", "[label]\nenv = prod\nteam = core\n")
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--config=" + path}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.Label, map[string]string{"env": "prod", "team": "core"} )
+	})
+
+    myTest("Given a map with a disallowed type", t, func() {
+		ShouldPanic(func(){
+			var my struct{ Label map[int]string }
+			NewFromArgs([]string{arg0}, &my)
+		})
+		ShouldPanic(func(){
+			var my struct{ Label map[string][]string }
+			NewFromArgs([]string{arg0}, &my)
+		})
+	})
+
+}
+
 func TestHasArgs( t *testing.T ) {
     myTest("Test HasArgs", t, func() {
 		{
@@ -536,3 +747,342 @@ func Test_args( t *testing.T ) {
 	})
 
 }
+
+func Test_groups( t *testing.T ) {
+
+	type Logging struct{
+		LogLevel	string	`default:"info"`
+		LogFile		string
+	}
+	type dbSt struct{
+		Host		string
+		Port		int		`port:Database port`
+	}
+	type mySt struct{
+		Verbose		bool
+		Logging
+		DB			dbSt
+		Replica		dbSt	`replica-db:REPLICA DATABASE`
+	}
+
+    myTest("Given embedded and nested option groups", t, func() {
+		var my mySt
+		argv := []string{arg0, "-v", "--log-level=debug", "-L", "x.log",
+			"--db-host=vogon", "--db-port", "5432", "--replica-db-host=magrathea", "--replica-db-port=5433"}
+		_,err := NewFromArgs(argv, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( my.LogLevel, "debug" )
+		ShouldEqual( my.LogFile, "x.log" )
+		ShouldEqual( my.DB.Host, "vogon" )
+		ShouldEqual( my.DB.Port, 5432 )
+		ShouldEqual( my.Replica.Host, "magrathea" )
+		ShouldEqual( my.Replica.Port, 5433 )
+	})
+
+    myTest("Given nested groups with defaults, environment and config", t, func() {
+		t.Setenv("MYAPP_DB_HOST", "vogon")
+		path := writeConfig(t, "my.conf", "[db]\nport = 5432\n")
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--config=" + path}, &my, EnvPrefix("MYAPP"))
+		ShouldNotError( err )
+		ShouldEqual( my.LogLevel, "info" )
+		ShouldEqual( my.DB.Host, "vogon" )
+		ShouldEqual( my.DB.Port, 5432 )
+	})
+
+    myTest("Given a private nested group", t, func() {
+		var my struct{ db dbSt }
+		ShouldPanic(func(){
+			NewFromArgs([]string{arg0}, &my)
+		})
+	})
+
+}
+
+func Test_required( t *testing.T ) {
+
+	type mySt struct{
+		Port	int		`opt:"p:port:number:" required:"true"`
+		Host	string	`required:"true"`
+		User	string	`required:"true" default:"arthur"`
+		Debug	bool
+	}
+
+    myTest("Given all required options", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-p", "80", "--host=vogon"}, &my)
+		ShouldNotError( err )
+		ShouldEqual( my.User, "arthur" )
+	})
+
+    myTest("Given required options from the environment", t, func() {
+		t.Setenv("MYAPP_HOST", "vogon")
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-p", "80"}, &my, EnvPrefix("MYAPP"))
+		ShouldNotError( err )
+	})
+
+    myTest("Given missing required options", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-d"}, &my)
+		ShouldError( err, "Missing required options: (-p/--port, -h/--host)" )
+		_,err = NewFromArgs([]string{arg0, "--port=80"}, &my)
+		ShouldError( err, "Missing required option: (-h/--host)" )
+	})
+
+    myTest("Given missing required options in a subcommand that was not invoked", t, func() {
+		var my mySt
+		var g struct{ Verbose bool }
+		_,err := NewFromArgs([]string{arg0, "-v"}, &g, NewCommand("serve", &my))
+		ShouldNotError( err )
+	})
+
+}
+
+func Test_endOfOptions( t *testing.T ) {
+
+	type mySt struct{
+		File	string
+		Verbose	bool
+	}
+
+    myTest("Given arguments after --", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-v", "--", "-f", "--file=x", `"quoted"`, "", "--"}, &my, &args)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( my.File, "" )
+		ShouldEqual( args, []string{"-f", "--file=x", `"quoted"`, "", "--"} )
+	})
+
+    myTest("Given -- after an option without a value", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-f", "--", "-v"}, &my, &args)
+		ShouldError( err, "Missing value for option: (f)" )
+	})
+
+    myTest("Given a lone dash", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-", "-v"}, &my, &args)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( args, []string{"-"} )
+		_,err = NewFromArgs([]string{arg0, "--file", "-"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( my.File, "-" )
+	})
+
+    myTest("Given too many arguments after --", t, func() {
+		var my mySt
+		args := make([]string, 0, 1)
+		_,err := NewFromArgs([]string{arg0, "--", "a", "b"}, &my, &args)
+		ShouldError( err, "number of arguments supplied exceeds limit (1)" )
+	})
+
+    myTest("Given a subcommand name after --", t, func() {
+		var my mySt
+		var args []string
+		op,err := NewFromArgs([]string{arg0, "--", "build"}, &my, &args, NewCommand("build"))
+		ShouldNotError( err )
+		ShouldEqual( op.Subcommand(), (*Option)(nil) )
+		ShouldEqual( args, []string{"build"} )
+	})
+
+}
+
+func Test_binding( t *testing.T ) {
+
+	type mySt struct{
+		Verbose	bool
+		Output	string
+		Count	int
+	}
+
+    myTest("Given a bool flag followed by an argument", t, func() {
+		var my mySt
+		var args [1]string
+		_,err := NewFromArgs([]string{arg0, "-v", "file.txt"}, &my, &args)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( args[0], "file.txt" )
+		_,err = NewFromArgs([]string{arg0, "--verbose", "a.txt", "b.txt"}, &my, &args)
+		ShouldError( err, "number of arguments supplied exceeds limit (1)" )
+	})
+
+    myTest("Given clustered flags where the last takes a value", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-vo", "out.txt", "in.txt"}, &my, &args)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( my.Output, "out.txt" )
+		ShouldEqual( args, []string{"in.txt"} )
+	})
+
+    myTest("Given an option value that looks like a subcommand", t, func() {
+		var my mySt
+		op,err := NewFromArgs([]string{arg0, "--output", "build", "-c", "-5"}, &my, NewCommand("build"))
+		ShouldNotError( err )
+		ShouldEqual( my.Output, "build" )
+		ShouldEqual( my.Count, -5 )
+		ShouldEqual( op.Subcommand(), (*Option)(nil) )
+	})
+
+    myTest("Given an option followed by a key instead of a value", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-o", "-v"}, &my)
+		ShouldError( err, "Missing value for option: (o)" )
+		_,err = NewFromArgs([]string{arg0, "-v", "--output"}, &my)
+		ShouldError( err, "Missing value for option: (output)" )
+	})
+
+}
+
+func Test_attachedValues( t *testing.T ) {
+
+	type mySt struct{
+		Port	int
+		Output	string
+		Num		int
+		Verbose	bool
+		Tags	[]string
+	}
+
+    myTest("Given values attached to unix keys", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-p8080", "-o/tmp/x", "-n-5", "-tone", "-t", "two", "-5"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( my.Port, 8080 )
+		ShouldEqual( my.Output, "/tmp/x" )
+		ShouldEqual( my.Num, -5 )
+		ShouldEqual( my.Tags, []string{"one","two"} )
+		ShouldEqual( args, []string{"-5"} )
+	})
+
+    myTest("Given clustered flags with a trailing value", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-vofile"}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+		ShouldEqual( my.Output, "file" )
+		my = mySt{}
+		_,err = NewFromArgs([]string{arg0, "-ovfile"}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( !my.Verbose )
+		ShouldEqual( my.Output, "vfile" )
+	})
+
+    myTest("Given a negative number as the value of a unix key", t, func() {
+		var my mySt
+		var args []string
+		_,err := NewFromArgs([]string{arg0, "-n", "-5", "-6"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( my.Num, -5 )
+		ShouldEqual( args, []string{"-6"} )
+	})
+
+    myTest("Given an unknown key in a cluster", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "-vx"}, &my)
+		ShouldError( err, "Invalid command line option: (x)" )
+	})
+
+}
+
+func Test_negativeNumbers( t *testing.T ) {
+
+	type mySt struct{
+		Offset	int
+		Temp	float64
+		Name	string
+	}
+
+    myTest("Given negative numbers as values and arguments", t, func() {
+		var my mySt
+		var args []float64
+		_,err := NewFromArgs([]string{arg0, "-o", "-42", "--temp", "-3.5", "-1e3", "-.5", "-n", "-"}, &my, &args)
+		ShouldNotError( err )
+		ShouldEqual( my.Offset, -42 )
+		ShouldEqual( my.Temp, -3.5 )
+		ShouldEqual( my.Name, "-" )
+		ShouldEqual( args, []float64{-1000, -0.5} )
+	})
+
+    myTest("Given digit keys without the DigitKeys setting", t, func() {
+		var my struct{ Best bool `opt:"9::Compress better"` }
+		ShouldPanic( func() {
+			NewFromArgs([]string{arg0}, &my)
+		})
+	})
+
+    myTest("Given digit keys", t, func() {
+		type digitSt struct{
+			Fast	bool	`opt:"1:fast:Compress faster"`
+			Best	bool	`opt:"9:best:Compress better"`
+			Level	int
+		}
+		var my digitSt
+		var args []int
+		_,err := NewFromArgs([]string{arg0, "-9", "-l", "-5", "-2"}, &my, &args, DigitKeys())
+		ShouldNotError( err )
+		ShouldBeTrue( my.Best )
+		ShouldBeTrue( !my.Fast )
+		ShouldEqual( my.Level, -5 )
+		ShouldEqual( args, []int{-2} )
+		my = digitSt{}
+		_,err = NewFromArgs([]string{arg0, "-l", "-1"}, &my, &args, DigitKeys())
+		ShouldError( err, "Missing value for option: (l)" )
+		my = digitSt{}
+		_,err = NewFromArgs([]string{arg0, "-l-1", "-19"}, &my, &args, DigitKeys())
+		ShouldNotError( err )
+		ShouldEqual( my.Level, -1 )
+		ShouldBeTrue( my.Fast && my.Best )
+	})
+
+}
+
+func Test_negation( t *testing.T ) {
+
+	type mySt struct{
+		Verbose		bool	`opt:"v:verbose::Say more" default:"true"`
+		Color		bool	`default:"true"`
+		NoColor		bool	`opt:":no-color::Never use color"`
+		Strict		bool	`opt:"s:strict::Fail early" negate:"false" default:"true"`
+		Name		string
+	}
+
+    myTest("Given a negated bool option", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--no-verbose"}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( !my.Verbose )
+		ShouldBeTrue( my.Color )
+		my = mySt{}
+		_,err = NewFromArgs([]string{arg0, "--no-verbose", "--verbose"}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( my.Verbose )
+	})
+
+    myTest("Given an option whose key starts with no-", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--no-color"}, &my)
+		ShouldNotError( err )
+		ShouldBeTrue( my.NoColor )
+		ShouldBeTrue( my.Color )
+	})
+
+    myTest("Given options that cannot be negated", t, func() {
+		var my mySt
+		_,err := NewFromArgs([]string{arg0, "--no-strict"}, &my)
+		ShouldError( err, "Invalid command line option: (no-strict)" )
+		my = mySt{}
+		_,err = NewFromArgs([]string{arg0, "--no-name"}, &my)
+		ShouldError( err, "Invalid command line option: (no-name)" )
+	})
+
+}
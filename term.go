@@ -0,0 +1,23 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"os"
+	"strconv"
+)
+
+// return the width of the terminal, if standard output is a terminal.  Tests
+// replace it to get the same help text on any terminal.
+var terminalWidth = stdoutWidth
+
+// return the width given by the COLUMNS environment variable
+func columnsEnv() (int, bool) {
+	w, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || w <= 0 {
+		return 0, false
+	}
+	return w, true
+}
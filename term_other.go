@@ -0,0 +1,21 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package option
+
+import (
+	"os"
+)
+
+// return the width of the terminal from the COLUMNS variable, if standard
+// output is a terminal
+func stdoutWidth() (int, bool) {
+	fi, err := os.Stdout.Stat()
+	if err != nil || fi.Mode() & os.ModeCharDevice == 0 {
+		return 0, false
+	}
+	return columnsEnv()
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package option
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// return the width of the terminal, if standard output is a terminal
+func stdoutWidth() (int, bool) {
+	var ws struct {
+		row, col, xpixel, ypixel uint16
+	}
+	_,_,errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(),
+		uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, false
+	}
+	if ws.col == 0 {
+		// a terminal that does not know its size
+		return columnsEnv()
+	}
+	return int(ws.col), true
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Mark K Mueller, markmueller.com
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE.md file.
+
+package option
+
+import (
+	"unicode"
+)
+
+// East Asian wide and fullwidth characters, and emoji, which take two columns
+// of a terminal
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1},	// Hangul Jamo
+		{0x231a, 0x231b, 1},	// watch, hourglass
+		{0x23e9, 0x23ec, 1},
+		{0x23f0, 0x23f3, 3},
+		{0x25fd, 0x25fe, 1},
+		{0x2614, 0x2615, 1},
+		{0x2648, 0x2653, 1},
+		{0x26a1, 0x26aa, 9},
+		{0x26bd, 0x26be, 1},
+		{0x26c4, 0x26c5, 1},
+		{0x26d4, 0x26ea, 22},
+		{0x26f2, 0x26f3, 1},
+		{0x26f5, 0x26fa, 5},
+		{0x26fd, 0x2705, 8},
+		{0x270a, 0x270b, 1},
+		{0x2728, 0x274c, 36},
+		{0x2753, 0x2755, 1},
+		{0x2757, 0x2795, 62},
+		{0x2796, 0x2797, 1},
+		{0x27b0, 0x27bf, 15},
+		{0x2b1b, 0x2b1c, 1},
+		{0x2b50, 0x2b55, 5},
+		{0x2e80, 0x303e, 1},	// CJK radicals, punctuation
+		{0x3041, 0x33ff, 1},	// Hiragana, Katakana, CJK compatibility
+		{0x3400, 0x4dbf, 1},	// CJK extension A
+		{0x4e00, 0x9fff, 1},	// CJK unified ideographs
+		{0xa000, 0xa4cf, 1},	// Yi
+		{0xa960, 0xa97f, 1},	// Hangul Jamo extended A
+		{0xac00, 0xd7a3, 1},	// Hangul syllables
+		{0xf900, 0xfaff, 1},	// CJK compatibility ideographs
+		{0xfe10, 0xfe19, 1},	// vertical forms
+		{0xfe30, 0xfe6f, 1},	// CJK compatibility forms
+		{0xff00, 0xff60, 1},	// fullwidth forms
+		{0xffe0, 0xffe6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x16fe0, 0x16fe4, 1},
+		{0x17000, 0x18cff, 1},	// Tangut
+		{0x1b000, 0x1b2ff, 1},	// Kana supplement
+		{0x1f004, 0x1f0cf, 203},
+		{0x1f18e, 0x1f191, 3},
+		{0x1f192, 0x1f19a, 1},
+		{0x1f200, 0x1f2ff, 1},	// enclosed ideographic supplement
+		{0x1f300, 0x1f64f, 1},	// pictographs, emoticons
+		{0x1f680, 0x1f6ff, 1},	// transport and map symbols
+		{0x1f7e0, 0x1f7eb, 1},
+		{0x1f90c, 0x1f9ff, 1},	// supplemental symbols and pictographs
+		{0x1fa70, 0x1faff, 1},
+		{0x20000, 0x2fffd, 1},	// CJK extension B and later
+		{0x30000, 0x3fffd, 1},
+	},
+}
+
+// zero width characters: combining marks, format characters and variation
+// selectors
+var zeroWidthRunes = []*unicode.RangeTable{unicode.Mn, unicode.Me, unicode.Cf}
+
+// return the number of terminal columns taken by a string
+func textWidth(s string) int {
+	width := 0
+	for _,r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// return the number of terminal columns taken by a rune
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x300:
+		// the common case
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+			return 0
+		}
+		return 1
+	case unicode.IsOneOf(zeroWidthRunes, r):
+		return 0
+	case unicode.Is(wideRunes, r):
+		return 2
+	}
+	return 1
+}